@@ -0,0 +1,77 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+)
+
+// UnusedIndex is a non-unique index that has never been scanned since the
+// statistics were last reset.
+type UnusedIndex struct {
+	Schema string `json:"schema"`
+	Table  string `json:"table"`
+	Index  string `json:"index"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// SeqScanTable is a large table read mostly by sequential scans, together
+// with the recorded statements touching it.
+type SeqScanTable struct {
+	Schema      string             `json:"schema"`
+	Table       string             `json:"table"`
+	SeqScans    int64              `json:"seq_scans"`
+	IdxScans    int64              `json:"idx_scans"`
+	RowEstimate int64              `json:"row_estimate"`
+	Queries     []QueryFingerprint `json:"queries"`
+}
+
+// IndexReport is the result of AdviseIndexes.
+type IndexReport struct {
+	UnusedIndexes []UnusedIndex
+	SeqScans      []SeqScanTable
+}
+
+// AdvisorOptions tunes AdviseIndexes. MinRows defaults to 10000.
+type AdvisorOptions struct {
+	MinRows int64
+}
+
+const unusedIndexesSql = `SELECT s.schemaname AS schema, s.relname AS "table", s.indexrelname AS index,
+	pg_relation_size(s.indexrelid) AS bytes
+FROM pg_stat_user_indexes s JOIN pg_index i ON i.indexrelid = s.indexrelid
+WHERE s.idx_scan = 0 AND NOT i.indisunique AND NOT i.indisprimary
+ORDER BY bytes DESC`
+
+const seqScansSql = `SELECT s.schemaname AS schema, s.relname AS "table", s.seq_scan AS seq_scans,
+	COALESCE(s.idx_scan, 0) AS idx_scans, GREATEST(c.reltuples, 0)::bigint AS row_estimate
+FROM pg_stat_user_tables s JOIN pg_class c ON c.oid = s.relid
+WHERE c.reltuples >= $1 AND s.seq_scan > COALESCE(s.idx_scan, 0)
+ORDER BY s.seq_scan DESC`
+
+// AdviseIndexes reports unused indexes and large tables dominated by
+// sequential scans, attaching the statements recorded by QueryFingerprints
+// that read each of those tables.
+func AdviseIndexes(ctx context.Context, db *sql.DB, opts AdvisorOptions) (*IndexReport, error) {
+	if opts.MinRows <= 0 {
+		opts.MinRows = 10000
+	}
+	unused, err := Query[[]UnusedIndex](ctx, db, unusedIndexesSql)
+	if err != nil {
+		return nil, err
+	}
+	seqScans, err := Query[[]SeqScanTable](ctx, db, seqScansSql, opts.MinRows)
+	if err != nil {
+		return nil, err
+	}
+	byTable := make(map[string][]QueryFingerprint)
+	for _, f := range QueryFingerprints() {
+		for _, table := range f.Tables {
+			byTable[table] = append(byTable[table], f)
+		}
+	}
+	report := &IndexReport{UnusedIndexes: *unused, SeqScans: *seqScans}
+	for i := range report.SeqScans {
+		report.SeqScans[i].Queries = byTable[report.SeqScans[i].Table]
+	}
+	return report, nil
+}
@@ -0,0 +1,70 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Archive moves the rows of T matching where into destTable, batchSize rows
+// per transaction, and returns the number of rows moved. Each batch deletes
+// from the source and inserts into the destination in one statement, so a
+// failure never loses or duplicates rows. Pass OnProgress among args to
+// follow long runs. An empty where archives every row.
+func Archive[T any](ctx context.Context, db *sql.DB, where string, destTable string, batchSize int, args ...any) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	args, opts := splitOptions(args)
+	where, args = parseSqlIn(where, args)
+	if strings.TrimSpace(where) == "" {
+		where = "TRUE"
+	}
+	table := opts.tableName(lookupHandle(db), new(T))
+	var columns []string
+	for _, f := range modelOf(lookupHandle(db), reflect.TypeOf(new(T)).Elem()).Fields {
+		if f.Generated == "" {
+			columns = append(columns, f.Column)
+		}
+	}
+	cols := strings.Join(columns, ",")
+	sqlStr := fmt.Sprintf(`WITH moved AS (
+	DELETE FROM %s WHERE ctid IN (SELECT ctid FROM %s WHERE %s LIMIT %d FOR UPDATE SKIP LOCKED)
+	RETURNING %s
+) INSERT INTO %s (%s) SELECT %s FROM moved`, table, table, where, batchSize, cols, destTable, cols, cols)
+	var total int64
+	for {
+		n, err := archiveBatch(ctx, db, sqlStr, table, args)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		opts.reportProgress(total)
+		if n < int64(batchSize) {
+			return total, nil
+		}
+	}
+}
+
+func archiveBatch(ctx context.Context, db *sql.DB, sqlStr, table string, args []any) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	result, err := tx.ExecContext(ctx, sqlStr, args...)
+	outputTimed(sqlStr, args, start)
+	if err != nil {
+		tx.Rollback()
+		return 0, wrapQueryError(err, sqlStr, table)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return n, tx.Commit()
+}
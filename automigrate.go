@@ -0,0 +1,306 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// AutoMigrate creates the table of T, or adds the columns it lacks, with
+// the indexes and comments declared on the model, in one transaction. It
+// never drops or alters existing columns. Column types follow the Go types
+// and these tags:
+//
+//	pri:"..."        primary key, several for a composite one (default id)
+//	type:"numeric"   column type, overriding the mapping
+//	size:"255"       varchar(255) for strings
+//	default:"x"      DEFAULT 'x'; defaultExpr:"now()" for an expression
+//	index:"" / index:"name"    index, fields sharing a name form one
+//	unique:"" / unique:"name"  unique index, grouped the same way
+//	comment:"..."    COMMENT ON COLUMN (TABLE on the blank field)
+//
+// Booleans, numbers and strings are NOT NULL; pointers, sql.Null* types and
+// everything else are nullable. Added columns are only NOT NULL when they
+// have a default, which Postgres needs to fill existing rows.
+func AutoMigrate[T any](ctx context.Context, db Executor) error {
+	db = route[T](db)
+	m := modelOf(handleFor(db), reflect.TypeOf(new(T)).Elem())
+	table := getTableName(new(T))
+	return Transaction(ctx, db, func(tx Executor) error {
+		existing, err := tableColumns(ctx, tx, table)
+		if err != nil {
+			return err
+		}
+		if err = requireGenerated(ctx, db, m, existing); err != nil {
+			return err
+		}
+		stmts := schemaSQL(m, table, existing)
+		stmts = append(stmts, CommentStatements[T]()...)
+		for _, stmt := range stmts {
+			start := time.Now()
+			if _, err = tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("automigrate %s: %w", table, err)
+			}
+			outputTimed(stmt, nil, start)
+		}
+		return nil
+	})
+}
+
+// requireGenerated fails with ErrUnsupported when AutoMigrate would add a
+// generated column the server cannot create.
+func requireGenerated(ctx context.Context, db Executor, m *model, existing map[string]bool) error {
+	for _, f := range m.Fields {
+		if f.Generated == "" || existing[f.Column] {
+			continue
+		}
+		server, err := serverVersion(ctx, db)
+		if err != nil {
+			return err
+		}
+		return server.Require(FeatureGeneratedColumns)
+	}
+	return nil
+}
+
+// CreateTableSQL returns the statements AutoMigrate runs for T against an
+// empty database, for review or for writing migrations by hand.
+func CreateTableSQL[T any]() []string {
+	m := modelOf(nil, reflect.TypeOf(new(T)).Elem())
+	return append(schemaSQL(m, getTableName(new(T)), nil), CommentStatements[T]()...)
+}
+
+// tableColumns returns the columns table has, none when it does not exist.
+func tableColumns(ctx context.Context, ex Executor, table string) (map[string]bool, error) {
+	schema, name := SplitTable(table)
+	rows, err := ex.QueryContext(ctx, `SELECT column_name FROM information_schema.columns
+		WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND table_name = $2`, schema, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var column string
+		if err = rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns[column] = true
+	}
+	return columns, rows.Err()
+}
+
+// schemaSQL returns the DDL bringing table to m given its existing columns.
+func schemaSQL(m *model, table string, existing map[string]bool) []string {
+	var stmts []string
+	if schema, _ := SplitTable(table); schema != "" {
+		stmts = append(stmts, "CREATE SCHEMA IF NOT EXISTS "+QuoteTable(schema))
+	}
+	var primary []string
+	for _, f := range m.Fields {
+		if f.Primary {
+			primary = append(primary, f.Column)
+		}
+	}
+	if len(existing) == 0 {
+		defs := make([]string, 0, len(m.Fields)+1)
+		for _, f := range m.Fields {
+			defs = append(defs, columnDDL(f, len(primary) == 1, false))
+		}
+		if len(primary) > 1 {
+			defs = append(defs, "PRIMARY KEY ("+strings.Join(primary, ", ")+")")
+		}
+		stmts = append(stmts, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s\n)", table, strings.Join(defs, ",\n\t")))
+	} else {
+		for _, f := range m.Fields {
+			if !existing[f.Column] {
+				stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s", table, columnDDL(f, false, true)))
+			}
+		}
+	}
+	return append(stmts, indexSQL(m, table)...)
+}
+
+// columnDDL returns the column definition of f. inlinePK marks a single
+// column primary key; adding restricts NOT NULL to columns with a default.
+func columnDDL(f *field, inlinePK, adding bool) string {
+	typ := columnType(f)
+	deflt := columnDefault(f)
+	if inlinePK && f.Primary && f.Tag.Get("type") == "" && deflt == "" {
+		if _, generated := idGenerator(f.Tag); !generated {
+			switch typ {
+			case "bigint":
+				typ = "bigserial"
+			case "integer":
+				typ = "serial"
+			case "smallint":
+				typ = "smallserial"
+			}
+		}
+	}
+	def := f.Column + " " + typ
+	if f.Generated != "" {
+		return def + " GENERATED ALWAYS AS (" + f.Generated + ") STORED"
+	}
+	if deflt != "" {
+		def += " DEFAULT " + deflt
+	}
+	if inlinePK && f.Primary {
+		return def + " PRIMARY KEY"
+	}
+	if !f.Primary && !nullableField(f) && (!adding || deflt != "") {
+		def += " NOT NULL"
+	}
+	return def
+}
+
+var uuidType = reflect.TypeOf(UUID{})
+
+var nullTypes = map[reflect.Type]string{
+	reflect.TypeOf(sql.NullString{}):  "text",
+	reflect.TypeOf(sql.NullInt64{}):   "bigint",
+	reflect.TypeOf(sql.NullInt32{}):   "integer",
+	reflect.TypeOf(sql.NullInt16{}):   "smallint",
+	reflect.TypeOf(sql.NullByte{}):    "smallint",
+	reflect.TypeOf(sql.NullFloat64{}): "double precision",
+	reflect.TypeOf(sql.NullBool{}):    "boolean",
+	reflect.TypeOf(sql.NullTime{}):    "timestamptz",
+}
+
+// columnType maps f to a Postgres type.
+func columnType(f *field) string {
+	if typ := f.Tag.Get("type"); typ != "" {
+		return typ
+	}
+	if f.Composite != "" {
+		return f.Composite
+	}
+	if f.Serializer != "" {
+		if f.Serializer == "json" {
+			return "jsonb"
+		}
+		return "text"
+	}
+	t := f.Type
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return "timestamptz"
+	}
+	if typ, ok := nullTypes[t]; ok {
+		return typ
+	}
+	if t == uuidType || t == reflect.TypeOf([16]byte{}) || f.Tag.Get("pri") == "uuid" {
+		return "uuid"
+	}
+	if _, ok := converterOf(t); ok {
+		return "text"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return "smallint"
+	case reflect.Int32, reflect.Uint16:
+		return "integer"
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return "bigint"
+	case reflect.Float32:
+		return "real"
+	case reflect.Float64:
+		return "double precision"
+	case reflect.String:
+		if size, err := strconv.Atoi(f.Tag.Get("size")); err == nil && size > 0 {
+			return fmt.Sprintf("varchar(%d)", size)
+		}
+		return "text"
+	}
+	if reflect.PointerTo(t).Implements(valuerType) || t.Implements(valuerType) {
+		return "text"
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		return "bytea"
+	}
+	return "jsonb"
+}
+
+// nullableField reports whether f allows NULL.
+func nullableField(f *field) bool {
+	if f.Composite != "" || f.Serializer != "" || f.Tag.Get("type") != "" || hasOrmOption(f.Tag, "forceNull") || hasOrmOption(f.Tag, "softDelete") {
+		return true
+	}
+	if _, ok := converterOf(f.Type); ok {
+		return true
+	}
+	switch f.Type.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return reflect.PointerTo(f.Type).Implements(valuerType)
+	}
+	return true
+}
+
+// columnDefault renders the defaultExpr or default tag of f.
+func columnDefault(f *field) string {
+	if expr := f.Tag.Get("defaultExpr"); expr != "" {
+		return expr
+	}
+	value, ok := f.Tag.Lookup("default")
+	if !ok {
+		return ""
+	}
+	switch f.Type.Kind() {
+	case reflect.Bool:
+		if _, err := strconv.ParseBool(value); err == nil {
+			return value
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return value
+		}
+	}
+	return pq.QuoteLiteral(value)
+}
+
+// indexSQL returns CREATE INDEX statements for the index and unique tags.
+// Default names follow Postgres: <table>_<columns>_idx and _key.
+func indexSQL(m *model, table string) []string {
+	_, base := SplitTable(table)
+	var stmts []string
+	for _, kind := range []struct{ tag, suffix, create string }{
+		{"unique", "key", "CREATE UNIQUE INDEX"},
+		{"index", "idx", "CREATE INDEX"},
+	} {
+		var names []string
+		groups := make(map[string][]string)
+		for _, f := range m.Fields {
+			name, ok := f.Tag.Lookup(kind.tag)
+			if !ok {
+				continue
+			}
+			if name == "" {
+				name = base + "_" + f.Column + "_" + kind.suffix
+			}
+			if _, seen := groups[name]; !seen {
+				names = append(names, name)
+			}
+			groups[name] = append(groups[name], f.Column)
+		}
+		for _, name := range names {
+			stmts = append(stmts, fmt.Sprintf("%s IF NOT EXISTS %s ON %s (%s)",
+				kind.create, QuoteTable(name), table, strings.Join(groups[name], ", ")))
+		}
+	}
+	return stmts
+}
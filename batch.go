@@ -0,0 +1,126 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// FailedRow is a row a batch skipped under SkipFailedRows.
+type FailedRow struct {
+	// Index is the position of the row in the slice passed in.
+	Index int
+	Row   any
+	Err   error
+}
+
+// BatchReport lists the rows of a batch Insert or Update that failed, for
+// retrying or parking in a dead letter table.
+type BatchReport struct {
+	Failed []FailedRow
+}
+
+// Err summarises the failures, nil when every row was written.
+func (r *BatchReport) Err() error {
+	switch len(r.Failed) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("orm: row %d failed: %w", r.Failed[0].Index, r.Failed[0].Err)
+	}
+	return fmt.Errorf("orm: %d rows failed, first row %d: %w", len(r.Failed), r.Failed[0].Index, r.Failed[0].Err)
+}
+
+// SkipFailedRows makes Insert and Update write each row under its own
+// savepoint and carry on past rows that fail, recording them in report
+// instead of rolling the batch back. Insert returns only the rows written.
+//
+//	var report orm.BatchReport
+//	rows, err := orm.Insert(ctx, db, events, orm.SkipFailedRows(&report))
+//	for _, f := range report.Failed {
+//		deadLetter(f.Row.(Event), f.Err)
+//	}
+func SkipFailedRows(report *BatchReport) QueryOption {
+	return queryOptionFunc(func(o *queryOptions) {
+		o.report = report
+	})
+}
+
+// writeRow runs write for row i of a batch in tx. With a report it runs in
+// a savepoint and a failure is recorded rather than returned; written tells
+// whether the row made it.
+func writeRow(ctx context.Context, tx *execTx, report *BatchReport, i int, row any, write func(Executor) error) (written bool, err error) {
+	if report == nil {
+		if err = write(tx); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	sp, err := beginTx(ctx, tx.Executor)
+	if err != nil {
+		return false, err
+	}
+	if err = write(sp); err != nil {
+		if rbErr := sp.Rollback(); rbErr != nil {
+			return false, rbErr
+		}
+		report.Failed = append(report.Failed, FailedRow{Index: i, Row: row, Err: err})
+		return false, nil
+	}
+	return true, sp.Commit()
+}
+
+// ExecBatch prepares sqlStr once and runs it for each parameter set in one
+// transaction, returning the result of every set. It suits repetitive writes
+// the struct helpers do not cover:
+//
+//	results, err := orm.ExecBatch(ctx, db, "UPDATE stock SET qty = qty - $2 WHERE sku = $1", [][]any{
+//		{"A-1", 2},
+//		{"B-7", 1},
+//	})
+//
+// A failing set rolls back the whole batch, unless options hold
+// SkipFailedRows: failed sets are then reported with their parameters as Row
+// and leave a nil result.
+func ExecBatch(ctx context.Context, db Executor, sqlStr string, paramSets [][]any, options ...QueryOption) ([]sql.Result, error) {
+	if len(paramSets) == 0 {
+		return nil, nil
+	}
+	opts := applyOptions(options)
+	d := handleFor(db).sqlDialect()
+	tx, err := beginTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	query, _ := rebind(d, sqlStr, paramSets[0])
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		return nil, wrapQueryError(err, sqlStr, "")
+	}
+	defer stmt.Close()
+	results := make([]sql.Result, len(paramSets))
+	for i, params := range paramSets {
+		_, err := writeRow(ctx, tx, opts.report, i, params, func(Executor) error {
+			start := time.Now()
+			_, args := rebind(d, sqlStr, params)
+			result, err := stmt.ExecContext(ctx, args...)
+			if err != nil {
+				return wrapQueryError(err, sqlStr, "")
+			}
+			outputTimed(sqlStr, params, start)
+			results[i] = result
+			return nil
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("orm: exec batch set %d: %w", i, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return results, nil
+}
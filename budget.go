@@ -0,0 +1,58 @@
+package orm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Budget splits the time left before a context's deadline across a planned
+// sequence of statements, so one slow statement cannot use up the whole
+// request. Time a step does not use carries over to the following steps.
+//
+//	b := orm.NewBudget(ctx, 0.7, 0.3)
+//	err := b.Run(ctx, func(ctx context.Context) error { ... main query ... })
+//	err = b.Run(ctx, func(ctx context.Context) error { ... count ... })
+type Budget struct {
+	mu       sync.Mutex
+	deadline time.Time
+	shares   []float64
+	next     int
+}
+
+// NewBudget plans one step per share; shares are relative weights. Without a
+// deadline on ctx the steps are not limited.
+func NewBudget(ctx context.Context, shares ...float64) *Budget {
+	b := &Budget{shares: shares}
+	b.deadline, _ = ctx.Deadline()
+	return b
+}
+
+// Next returns the context for the next step, limited to the step's share of
+// the remaining time. Steps beyond the plan get whatever time is left.
+func (b *Budget) Next(ctx context.Context) (context.Context, context.CancelFunc) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.deadline.IsZero() {
+		return context.WithCancel(ctx)
+	}
+	remaining := time.Until(b.deadline)
+	if b.next < len(b.shares) {
+		var left float64
+		for _, share := range b.shares[b.next:] {
+			left += share
+		}
+		if left > 0 {
+			remaining = time.Duration(float64(remaining) * b.shares[b.next] / left)
+		}
+		b.next++
+	}
+	return context.WithTimeout(ctx, remaining)
+}
+
+// Run runs fn as the next step.
+func (b *Budget) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := b.Next(ctx)
+	defer cancel()
+	return fn(ctx)
+}
@@ -0,0 +1,130 @@
+package orm
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Builder assembles a SELECT on T's table step by step:
+//
+//	users, err := orm.Table[User](db).Where("age > $1", 18).OrderBy("created_at DESC").Limit(10).Find(ctx)
+//
+// Each Where fragment numbers its placeholders from $1; they are renumbered
+// when fragments are combined.
+type Builder[T any] struct {
+	db      Executor
+	q       ListQuery
+	wheres  []string
+	options []QueryOption
+	// source is a raw query used in place of T's table.
+	source string
+}
+
+// Table starts a query on T's table.
+func Table[T any](db Executor) *Builder[T] {
+	return &Builder[T]{db: db}
+}
+
+// FromSQL wraps a hand-written query so it can be refined with Where,
+// OrderBy, Limit and the other builder steps. The query becomes a subquery;
+// its $N placeholders come first and later fragments are numbered after them.
+func FromSQL[T any](db Executor, sqlStr string, args ...any) *Builder[T] {
+	return &Builder[T]{db: db, source: sqlStr, q: ListQuery{Args: args}}
+}
+
+// Unscoped includes soft deleted rows.
+func (b *Builder[T]) Unscoped() *Builder[T] {
+	return b.With(Unscoped())
+}
+
+// Select restricts the selected columns; by default all columns are read.
+func (b *Builder[T]) Select(columns ...string) *Builder[T] {
+	b.q.Columns = append(b.q.Columns, columns...)
+	return b
+}
+
+// Where adds a condition, combined with earlier ones by AND.
+func (b *Builder[T]) Where(cond string, args ...any) *Builder[T] {
+	b.wheres = append(b.wheres, "("+shiftParams(cond, len(b.q.Args))+")")
+	b.q.Args = append(b.q.Args, args...)
+	return b
+}
+
+// OrderBy appends ORDER BY terms such as "created_at DESC".
+func (b *Builder[T]) OrderBy(terms ...string) *Builder[T] {
+	b.q.OrderBy = append(b.q.OrderBy, terms...)
+	return b
+}
+
+// Limit caps the number of rows; zero means no limit.
+func (b *Builder[T]) Limit(n int) *Builder[T] {
+	b.q.Limit = n
+	return b
+}
+
+// Offset skips the first n rows.
+func (b *Builder[T]) Offset(n int) *Builder[T] {
+	b.q.Offset = n
+	return b
+}
+
+// With adds query options such as OnTable or AfterScan.
+func (b *Builder[T]) With(options ...QueryOption) *Builder[T] {
+	b.options = append(b.options, options...)
+	return b
+}
+
+// ToSQL returns the statement and its arguments, for running it by hand or
+// passing it on to FromSQL.
+func (b *Builder[T]) ToSQL() (string, []any) {
+	q := b.listQuery()
+	return q.SQL(b.from()), q.Args
+}
+
+// Find runs the query and returns the matching rows.
+func (b *Builder[T]) Find(ctx context.Context) ([]T, error) {
+	sqlStr, args := b.ToSQL()
+	for _, opt := range b.options {
+		args = append(args, opt)
+	}
+	rows, err := Query[[]T](ctx, b.db, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	return *rows, nil
+}
+
+func (b *Builder[T]) from() string {
+	if b.source != "" {
+		return "(" + b.source + ") AS orm_sub"
+	}
+	return applyOptions(b.options).tableName(handleFor(route[T](b.db)), new(T))
+}
+
+func (b *Builder[T]) listQuery() ListQuery {
+	q := b.q
+	q.Where = strings.Join(b.wheres, " AND ")
+	if b.source == "" {
+		q.Where = scopedWhere[T](handleFor(route[T](b.db)), q.Where, applyOptions(b.options))
+	}
+	return q
+}
+
+// shiftParams adds offset to every $N placeholder of a SQL fragment.
+func shiftParams(fragment string, offset int) string {
+	if offset == 0 || !strings.Contains(fragment, "$") {
+		return fragment
+	}
+	var b strings.Builder
+	for _, tok := range lexSQL(fragment) {
+		if tok.kind == tokParam {
+			if n, err := strconv.Atoi(tok.text[1:]); err == nil {
+				b.WriteString("$" + strconv.Itoa(n+offset))
+				continue
+			}
+		}
+		b.WriteString(tok.text)
+	}
+	return b.String()
+}
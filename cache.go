@@ -0,0 +1,51 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+)
+
+// InvalidateChannel is the NOTIFY channel used by NotifyInvalidate.
+const InvalidateChannel = "orm_invalidate"
+
+// caches lists the reset of every cache InvalidateCaches drops.
+var caches = []func(){resetModels, resetSnakeCache, resetServers}
+
+// InvalidateCaches drops every cached mapping, so the next call re-reads
+// struct metadata. Call it after migrations in long running services.
+func InvalidateCaches() {
+	for _, reset := range caches {
+		reset()
+	}
+}
+
+// NotifyInvalidate broadcasts a cache invalidation to every process watching
+// InvalidateChannel, typically right after a migration.
+func NotifyInvalidate(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, "SELECT pg_notify($1, '')", InvalidateChannel)
+	return err
+}
+
+// WatchInvalidations calls InvalidateCaches for every value received from
+// notifications until ctx is done or the channel is closed. It accepts the
+// notification channel of any driver, e.g. with lib/pq:
+//
+//	l := pq.NewListener(dsn, time.Second, time.Minute, nil)
+//	l.Listen(orm.InvalidateChannel)
+//	go orm.WatchInvalidations(ctx, l.Notify)
+//
+// lib/pq sends nil after reconnecting; that invalidates too, since
+// notifications may have been missed.
+func WatchInvalidations[N any](ctx context.Context, notifications <-chan N) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-notifications:
+			if !ok {
+				return
+			}
+			InvalidateCaches()
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package orm
+
+import (
+	"database/sql"
+	"reflect"
+	"time"
+)
+
+// Clock tells the time written by the ORM itself: auto timestamps, soft
+// delete marks and TTL cutoffs.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to Clock.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// FixedClock returns a Clock stopped at t, for tests.
+func FixedClock(t time.Time) Clock {
+	return ClockFunc(func() time.Time { return t })
+}
+
+// SetClock makes writes through db take their timestamps from c; nil goes
+// back to the default. With a clock set, zero created_at and updated_at
+// fields are filled on Insert, updated_at is refreshed on Update and soft
+// deletes bind the clock's time; without one, those are left to the
+// database (column defaults and CURRENT_TIMESTAMP).
+//
+//	orm.SetClock(db, orm.FixedClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+func SetClock(db *sql.DB, c Clock) {
+	h := handleOf(db)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clock = c
+}
+
+// clockOf returns the clock set on h, nil when there is none.
+func (h *handle) clockOf() Clock {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.clock
+}
+
+// now returns the time of h's clock, or the wall clock.
+func (h *handle) now() time.Time {
+	if c := h.clockOf(); c != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// autoTimestamp reports whether column is stamped by the clock on insert
+// (created_at, updated_at) or on update (updated_at only).
+func autoTimestamp(column string, update bool) bool {
+	return column == "updated_at" || (!update && column == "created_at")
+}
+
+// stampTimes sets the auto timestamp fields of row from h's clock: zero ones
+// on insert, updated_at always on update. It does nothing without a clock.
+func stampTimes(h *handle, row any, update bool) {
+	c := h.clockOf()
+	if c == nil {
+		return
+	}
+	rv := reflect.ValueOf(row).Elem()
+	var now reflect.Value
+	for _, f := range modelOf(h, rv.Type()).Fields {
+		if !autoTimestamp(f.Column, update) {
+			continue
+		}
+		fv := rv.FieldByIndex(f.Index)
+		if !update && !fv.IsZero() {
+			continue
+		}
+		if !now.IsValid() {
+			now = reflect.ValueOf(c.Now())
+		}
+		switch f.Type {
+		case timeType:
+			fv.Set(now)
+		case reflect.PointerTo(timeType):
+			p := reflect.New(timeType)
+			p.Elem().Set(now)
+			fv.Set(p)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Command ormsh runs builder expressions and SQL against a Postgres database
+// interactively. The connection comes from the libpq environment variables,
+// overridden by flags.
+//
+// A plain build knows no models, so only SQL is useful. To query your own
+// models, copy this file into your module and import the package that calls
+// orm.Register for them:
+//
+//	import _ "example.com/app/models"
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/gobkc/orm"
+	"github.com/gobkc/orm/ormsh"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	dsn, _ := orm.FromEnv()
+	flag.StringVar(&dsn.Host, "host", dsn.Host, "database host")
+	flag.IntVar(&dsn.Port, "port", dsn.Port, "database port")
+	flag.StringVar(&dsn.User, "user", dsn.User, "database user")
+	flag.StringVar(&dsn.Database, "db", dsn.Database, "database name")
+	sslMode := flag.String("sslmode", string(dsn.SSLMode), "sslmode")
+	flag.Parse()
+	dsn.SSLMode = orm.SSLMode(*sslMode)
+	if dsn.ApplicationName == "" {
+		dsn.ApplicationName = "ormsh"
+	}
+	if err := dsn.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "ormsh:", err)
+		os.Exit(2)
+	}
+	db, err := sql.Open("postgres", dsn.String())
+	if err == nil {
+		err = db.Ping()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ormsh:", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+	fmt.Printf("connected to %s@%s/%s, \\? for help\n", dsn.User, dsn.Host, dsn.Database)
+	shell := &ormsh.Shell{DB: db, In: os.Stdin, Out: os.Stdout, Prompt: "> "}
+	if err = shell.Run(context.Background()); err != nil {
+		fmt.Fprintln(os.Stderr, "ormsh:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,34 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/lib/pq"
+)
+
+// CommentStatements returns COMMENT ON statements for the comment tags of T.
+// The table comment goes on the blank field, next to its database binding:
+//
+//	type Invoice struct {
+//		_     struct{} `comment:"Issued invoices, one per order"`
+//		Total int64    `comment:"Amount in cents"`
+//	}
+func CommentStatements[T any]() []string {
+	t := reflect.TypeOf(new(T)).Elem()
+	table := getTableName(new(T))
+	var stmts []string
+	for i := 0; i < t.NumField(); i++ {
+		if sf := t.Field(i); sf.Name == "_" {
+			if text, ok := sf.Tag.Lookup("comment"); ok {
+				stmts = append(stmts, fmt.Sprintf("COMMENT ON TABLE %s IS %s", table, pq.QuoteLiteral(text)))
+			}
+		}
+	}
+	for _, f := range modelOf(nil, t).Fields {
+		if text, ok := f.Tag.Lookup("comment"); ok {
+			stmts = append(stmts, fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s", table, f.Column, pq.QuoteLiteral(text)))
+		}
+	}
+	return stmts
+}
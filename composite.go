@@ -0,0 +1,217 @@
+package orm
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrComposite = errors.New("composite: malformed value")
+
+// compositeScanner decodes the text form of a Postgres composite value,
+// e.g. ("1 Main St",Springfield,12345), into the mapped fields of a struct
+// in declaration order; unexported and ignored fields are skipped.
+type compositeScanner struct {
+	v reflect.Value
+}
+
+func (s *compositeScanner) Scan(src any) error {
+	var text string
+	switch v := src.(type) {
+	case nil:
+		s.v.Set(reflect.Zero(s.v.Type()))
+		return nil
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	default:
+		return fmt.Errorf("%w: unexpected %T", ErrComposite, src)
+	}
+	dest := s.v
+	if dest.Kind() == reflect.Pointer {
+		dest.Set(reflect.New(dest.Type().Elem()))
+		dest = dest.Elem()
+	}
+	attrs, err := parseComposite(text)
+	if err != nil {
+		return err
+	}
+	fields := columnFields(dest.Type())
+	if len(attrs) != len(fields) {
+		return fmt.Errorf("%w: %d attributes for %d fields of %s", ErrComposite, len(attrs), len(fields), dest.Type())
+	}
+	for i, attr := range attrs {
+		fv := dest.FieldByIndex(fields[i].Index)
+		if attr == nil {
+			fv.Set(reflect.Zero(fv.Type()))
+			continue
+		}
+		if err = setText(fv, *attr); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrComposite, fields[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// parseComposite splits the text of a composite value into attributes; nil
+// marks a NULL attribute.
+func parseComposite(text string) ([]*string, error) {
+	if len(text) < 2 || text[0] != '(' || text[len(text)-1] != ')' {
+		return nil, fmt.Errorf("%w: %q", ErrComposite, text)
+	}
+	body := text[1 : len(text)-1]
+	var (
+		attrs  []*string
+		cur    strings.Builder
+		quoted bool
+		inQ    bool
+	)
+	flush := func() {
+		if cur.Len() == 0 && !quoted {
+			attrs = append(attrs, nil)
+		} else {
+			s := cur.String()
+			attrs = append(attrs, &s)
+		}
+		cur.Reset()
+		quoted = false
+	}
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case inQ && c == '\\' && i+1 < len(body):
+			i++
+			cur.WriteByte(body[i])
+		case inQ && c == '"' && i+1 < len(body) && body[i+1] == '"':
+			i++
+			cur.WriteByte('"')
+		case c == '"':
+			inQ, quoted = !inQ, true
+		case !inQ && c == ',':
+			flush()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	if inQ {
+		return nil, fmt.Errorf("%w: unterminated quote in %q", ErrComposite, text)
+	}
+	flush()
+	return attrs, nil
+}
+
+// setText assigns the text form of a scalar to v.
+func setText(v reflect.Value, text string) error {
+	if v.Kind() == reflect.Pointer {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	if sc, ok := v.Addr().Interface().(interface{ Scan(any) error }); ok {
+		return sc.Scan(text)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		v.SetBool(text == "t" || text == "true")
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			t, err := parseTimestamp(text)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(t))
+			return nil
+		}
+		return (&compositeScanner{v: v}).Scan(text)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func parseTimestamp(text string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999-07", "2006-01-02 15:04:05.999999999-07:00", "2006-01-02 15:04:05.999999999", "2006-01-02"} {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", text)
+}
+
+// compositeText renders a struct in the composite text format.
+func compositeText(v reflect.Value) (string, bool) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	fields := columnFields(v.Type())
+	parts := make([]string, len(fields))
+	for i, sf := range fields {
+		fv := v.FieldByIndex(sf.Index)
+		if fv.Kind() == reflect.Pointer && fv.IsNil() {
+			continue
+		}
+		var text string
+		if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{}) {
+			if _, ok := fv.Interface().(driver.Valuer); !ok {
+				text, _ = compositeText(fv)
+				parts[i] = quoteCompositeAttr(text)
+				continue
+			}
+		}
+		switch val := fv.Interface().(type) {
+		case time.Time:
+			text = val.Format("2006-01-02 15:04:05.999999999Z07:00")
+		case driver.Valuer:
+			dv, err := val.Value()
+			if err != nil || dv == nil {
+				continue
+			}
+			text = fmt.Sprintf("%v", dv)
+		case bool:
+			text = "f"
+			if val {
+				text = "t"
+			}
+		default:
+			text = fmt.Sprintf("%v", reflect.Indirect(fv).Interface())
+		}
+		parts[i] = quoteCompositeAttr(text)
+	}
+	return "(" + strings.Join(parts, ",") + ")", true
+}
+
+func quoteCompositeAttr(s string) string {
+	if s != "" && !strings.ContainsAny(s, "\"\\(),' \t\n") {
+		return s
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `""`)
+	return `"` + s + `"`
+}
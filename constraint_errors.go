@@ -0,0 +1,122 @@
+package orm
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// constraintMessages are the FieldErrors messages per SQLSTATE.
+var constraintMessages = map[string]string{
+	"23505": "already taken",
+	"23514": "is invalid",
+	"23503": "references a missing or still referenced record",
+	"23502": "is required",
+}
+
+// violation is the driver independent part of a constraint error.
+type violation struct {
+	code       string
+	constraint string
+	column     string
+	detail     string
+}
+
+// violationOf extracts a violation from lib/pq errors, or from any error with
+// a SQLState method and ConstraintName / ColumnName / Detail fields (pgx).
+func violationOf(err error) (violation, bool) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return violation{string(pqErr.Code), pqErr.Constraint, pqErr.Column, pqErr.Detail}, true
+	}
+	var state interface{ SQLState() string }
+	if !errors.As(err, &state) {
+		return violation{}, false
+	}
+	v := violation{code: state.SQLState()}
+	rv := reflect.Indirect(reflect.ValueOf(state))
+	if rv.Kind() == reflect.Struct {
+		str := func(name string) string {
+			if f := rv.FieldByName(name); f.IsValid() && f.Kind() == reflect.String {
+				return f.String()
+			}
+			return ""
+		}
+		v.constraint, v.column, v.detail = str("ConstraintName"), str("ColumnName"), str("Detail")
+	}
+	return v, true
+}
+
+// detailKey matches `Key (email)=(a@b.c) already exists.` style details.
+var detailKey = regexp.MustCompile(`Key \(([^)]*)\)=`)
+
+// ConstraintErrors maps a unique, check, foreign key or not-null violation
+// raised for T's table to FieldErrors keyed by T's Go field names, e.g.
+// {"Email": "already taken"}. Fields are found through, in order: a
+// `constraint:"name"` tag on the field, the column named by the error, the
+// key columns in its detail, and Postgres' default constraint naming
+// (<table>_<column>_key, _check, _fkey). ok is false when err is not a
+// constraint violation or no field matched.
+func ConstraintErrors[T any](db Executor, err error) (errs FieldErrors, ok bool) {
+	v, found := violationOf(err)
+	msg, known := constraintMessages[v.code]
+	if !found || !known {
+		return nil, false
+	}
+	m := modelOf(handleFor(db), reflect.TypeOf(new(T)).Elem())
+	errs = FieldErrors{}
+	add := func(column string) {
+		if f, ok := m.selectable(strings.Trim(strings.TrimSpace(column), `"`)); ok {
+			errs[f.Name] = msg
+		}
+	}
+	for _, f := range m.Fields {
+		if v.constraint != "" && f.Tag.Get("constraint") == v.constraint {
+			errs[f.Name] = msg
+		}
+	}
+	if len(errs) == 0 && v.column != "" {
+		add(v.column)
+	}
+	if len(errs) == 0 {
+		if match := detailKey.FindStringSubmatch(v.detail); match != nil {
+			for _, column := range strings.Split(match[1], ",") {
+				add(column)
+			}
+		}
+	}
+	if len(errs) == 0 && v.constraint != "" {
+		for _, column := range constraintColumns(m, rawTableName(new(T)), v.constraint) {
+			add(column)
+		}
+	}
+	return errs, len(errs) > 0
+}
+
+// constraintColumns splits a default constraint name such as
+// users_tenant_id_email_key into the model's columns.
+func constraintColumns(m *model, table, name string) []string {
+	_, table = SplitTable(table)
+	name = strings.TrimPrefix(name, table+"_")
+	for _, suffix := range []string{"_key", "_check", "_fkey", "_idx", "_unique"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	var columns []string
+	for name != "" {
+		matched := ""
+		for _, f := range m.Fields {
+			if (name == f.Column || strings.HasPrefix(name, f.Column+"_")) && len(f.Column) > len(matched) {
+				matched = f.Column
+			}
+		}
+		if matched == "" {
+			return nil
+		}
+		columns = append(columns, matched)
+		name = strings.TrimPrefix(strings.TrimPrefix(name, matched), "_")
+	}
+	return columns
+}
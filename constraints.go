@@ -0,0 +1,72 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrNotInTransaction = errors.New("orm: executor is not a transaction")
+
+// DeferConstraints postpones the named constraints (all deferrable ones when
+// names is empty) until tx commits, so rows referencing each other can be
+// written in any order. Only constraints declared DEFERRABLE are affected.
+// tx is a *sql.Tx, possibly bound with Use, or the executor Transaction
+// passes to its callback.
+func DeferConstraints(ctx context.Context, tx Executor, names ...string) error {
+	return setConstraints(ctx, tx, "DEFERRED", names)
+}
+
+// ImmediateConstraints checks the named constraints (or all) right away,
+// including anything deferred so far in tx.
+func ImmediateConstraints(ctx context.Context, tx Executor, names ...string) error {
+	return setConstraints(ctx, tx, "IMMEDIATE", names)
+}
+
+func setConstraints(ctx context.Context, ex Executor, mode string, names []string) error {
+	tx, ok := txOf(ex)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrNotInTransaction, ex)
+	}
+	list := "ALL"
+	if len(names) > 0 {
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = quoteQualified(name)
+		}
+		list = strings.Join(quoted, ", ")
+	}
+	sqlStr := "SET CONSTRAINTS " + list + " " + mode
+	defer outputTimed(sqlStr, nil, time.Now())
+	_, err := tx.ExecContext(ctx, sqlStr)
+	return err
+}
+
+// txOf returns the *sql.Tx behind ex, looking through bindings and the
+// ORM's own transactions and savepoints.
+func txOf(ex Executor) (*sql.Tx, bool) {
+	for {
+		switch e := rawExecutor(ex).(type) {
+		case *sql.Tx:
+			return e, true
+		case *execTx:
+			ex = e.Executor
+		default:
+			return nil, false
+		}
+	}
+}
+
+// quoteQualified quotes each part of a possibly schema qualified name.
+func quoteQualified(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = pq.QuoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,87 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// converter is the type-erased form of a registered conversion.
+type converter struct {
+	encode func(v reflect.Value) (any, error)
+	decode func(src any, dest reflect.Value) error
+}
+
+var converters sync.Map
+
+// RegisterConverter makes fields of type T (and *T) go through encode when
+// written and decode when scanned, ahead of the kind based handling. encode
+// returns a driver value (int64, float64, bool, []byte, string, time.Time or
+// nil); decode receives the raw column value, never NULL, which leaves the
+// field zero.
+//
+//	orm.RegisterConverter(
+//		func(s Status) (any, error) { return s.String(), nil },
+//		func(src any) (Status, error) { return ParseStatus(fmt.Sprint(src)) },
+//	)
+func RegisterConverter[T any](encode func(T) (any, error), decode func(src any) (T, error)) {
+	converters.Store(reflect.TypeOf(new(T)).Elem(), converter{
+		encode: func(v reflect.Value) (any, error) {
+			return encode(v.Interface().(T))
+		},
+		decode: func(src any, dest reflect.Value) error {
+			if b, ok := src.([]byte); ok {
+				src = string(b)
+			}
+			v, err := decode(src)
+			if err != nil {
+				return err
+			}
+			dest.Set(reflect.ValueOf(&v).Elem())
+			return nil
+		},
+	})
+}
+
+func converterOf(t reflect.Type) (converter, bool) {
+	c, ok := converters.Load(t)
+	if !ok {
+		return converter{}, false
+	}
+	return c.(converter), true
+}
+
+// convertedField returns the converter of a T or *T field.
+func convertedField(t reflect.Type) (converter, bool) {
+	if c, ok := converterOf(t); ok {
+		return c, true
+	}
+	if t.Kind() == reflect.Pointer {
+		return converterOf(t.Elem())
+	}
+	return converter{}, false
+}
+
+// convertedScanner decodes a column into a field with a registered converter.
+type convertedScanner struct {
+	v    reflect.Value
+	conv converter
+}
+
+func (s *convertedScanner) Scan(src any) error {
+	if src == nil {
+		s.v.Set(reflect.Zero(s.v.Type()))
+		return nil
+	}
+	dest := s.v
+	if dest.Kind() == reflect.Pointer {
+		if _, ok := converterOf(dest.Type()); !ok {
+			dest.Set(reflect.New(dest.Type().Elem()))
+			dest = dest.Elem()
+		}
+	}
+	if err := s.conv.decode(src, dest); err != nil {
+		return fmt.Errorf("orm: converting %T into %s: %w", src, s.v.Type(), err)
+	}
+	return nil
+}
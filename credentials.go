@@ -0,0 +1,83 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Credentials are the login used for a new connection. Password may hold an
+// auth token such as an RDS IAM token.
+type Credentials struct {
+	User     string
+	Password string
+}
+
+// CredentialProvider returns the credentials currently valid. It is called
+// each time the pool opens a connection.
+type CredentialProvider func(ctx context.Context) (Credentials, error)
+
+type credentialConnector struct {
+	dsn      DSN
+	provider CredentialProvider
+}
+
+// NewConnector returns a lib/pq connector that asks provider for the user and
+// password on every new connection, so rotated secrets are picked up without
+// a restart. Pair it with db.SetConnMaxLifetime to cycle old connections.
+func NewConnector(dsn DSN, provider CredentialProvider) driver.Connector {
+	return &credentialConnector{dsn: dsn, provider: provider}
+}
+
+// OpenWithCredentials opens a pool over NewConnector.
+func OpenWithCredentials(dsn DSN, provider CredentialProvider) *sql.DB {
+	return sql.OpenDB(NewConnector(dsn, provider))
+}
+
+func (c *credentialConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	creds, err := c.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dsn := c.dsn
+	if creds.User != "" {
+		dsn.User = creds.User
+	}
+	dsn.Password = creds.Password
+	conn, err := pq.NewConnector(dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	return conn.Connect(ctx)
+}
+
+func (c *credentialConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+// CachedCredentials wraps provider so it is called at most once per ttl,
+// which suits tokens that are valid for a while but costly to mint.
+func CachedCredentials(provider CredentialProvider, ttl time.Duration) CredentialProvider {
+	var (
+		mu      sync.Mutex
+		cached  Credentials
+		expires time.Time
+	)
+	return func(ctx context.Context) (Credentials, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if time.Now().Before(expires) {
+			return cached, nil
+		}
+		creds, err := provider(ctx)
+		if err != nil {
+			return creds, err
+		}
+		cached, expires = creds, time.Now().Add(ttl)
+		return creds, nil
+	}
+}
@@ -0,0 +1,82 @@
+package orm
+
+import (
+	"database/sql"
+	"reflect"
+	"sync"
+)
+
+var databases sync.Map
+
+// RegisterDB names db so models can be bound to it with a blank field tag:
+//
+//	type Invoice struct {
+//		_  struct{} `db:"billing"`
+//		ID int64
+//	}
+//
+// Registering nil removes the name.
+func RegisterDB(name string, db *sql.DB) {
+	if db == nil {
+		databases.Delete(name)
+		return
+	}
+	databases.Store(name, db)
+}
+
+// DB returns the database registered under name, or nil.
+func DB(name string) *sql.DB {
+	if db, ok := databases.Load(name); ok {
+		return db.(*sql.DB)
+	}
+	return nil
+}
+
+// DatabaseOf returns the registered name T is bound to, or "".
+func DatabaseOf[T any]() string {
+	return databaseOf(reflect.TypeOf(new(T)).Elem())
+}
+
+// databaseOf reads the db tag of the blank field of t, looking through
+// pointers and slices so Query[[]T] routes like T.
+func databaseOf(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return ""
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if sf := t.Field(i); sf.Name == "_" {
+			if name := sf.Tag.Get("db"); name != "" && name != "-" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// route returns the registered database of T in place of db when db is nil
+// or a plain *sql.DB. Transactions and connections are the caller's explicit
+// choice and are kept as is.
+func route[T any](db Executor) Executor {
+	name := databaseOf(reflect.TypeOf(new(T)).Elem())
+	if name == "" {
+		return db
+	}
+	target := DB(name)
+	if target == nil {
+		return db
+	}
+	switch e := db.(type) {
+	case nil:
+		return target
+	case *sql.DB:
+		return target
+	case *boundExecutor:
+		if _, ok := e.Executor.(*sql.DB); ok {
+			return target
+		}
+	}
+	return db
+}
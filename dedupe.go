@@ -0,0 +1,107 @@
+package orm
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+type dedupeOption struct {
+	keys []string
+	last bool
+}
+
+// DedupeFirst makes Insert drop rows repeating the natural key formed by
+// keys (field or column names), keeping the first occurrence. Duplicates in
+// upstream data would otherwise hit a unique constraint mid-transaction.
+// Rows with a NULL in the key are always kept, as NULLs never collide in a
+// unique constraint.
+func DedupeFirst(keys ...string) QueryOption {
+	return queryOptionFunc(func(o *queryOptions) {
+		o.dedupe = &dedupeOption{keys: keys}
+	})
+}
+
+// DedupeLast is DedupeFirst keeping the last occurrence, in its position.
+func DedupeLast(keys ...string) QueryOption {
+	return queryOptionFunc(func(o *queryOptions) {
+		o.dedupe = &dedupeOption{keys: keys, last: true}
+	})
+}
+
+// dedupeRows applies the dedupe option to rows.
+func dedupeRows[T any](h *handle, rows []T, opt *dedupeOption) ([]T, error) {
+	if opt == nil || len(rows) == 0 {
+		return rows, nil
+	}
+	m := modelOf(h, reflect.TypeOf(new(T)).Elem())
+	fields := make([]*field, len(opt.keys))
+	for i, name := range opt.keys {
+		f, ok := m.selectable(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrFilter, name)
+		}
+		fields[i] = f
+	}
+	keys := make([]string, len(rows))
+	null := make([]bool, len(rows))
+	index := make(map[string]int, len(rows))
+	for i, row := range rows {
+		v := reflect.ValueOf(row)
+		parts := make([]string, len(fields))
+		for j, f := range fields {
+			part, ok, err := dedupeKey(f, v.FieldByIndex(f.Index))
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				null[i] = true
+				break
+			}
+			parts[j] = part
+		}
+		if null[i] {
+			continue
+		}
+		keys[i] = strings.Join(parts, "\x00")
+		if _, seen := index[keys[i]]; !seen || opt.last {
+			index[keys[i]] = i
+		}
+	}
+	kept := make([]T, 0, len(rows))
+	for i, row := range rows {
+		if null[i] || index[keys[i]] == i {
+			kept = append(kept, row)
+		}
+	}
+	if len(kept) == len(rows) {
+		return rows, nil
+	}
+	return kept, nil
+}
+
+// dedupeKey renders the value a field is written as, so pointers compare by
+// what they point to and Valuers by the value they produce. ok is false when
+// the field is written as NULL.
+func dedupeKey(f *field, v reflect.Value) (key string, ok bool, err error) {
+	arg, cast, _, err := bindValue(reflect.StructField{Tag: f.Tag}, v)
+	if err != nil {
+		return "", false, err
+	}
+	if valuer, isValuer := arg.(driver.Valuer); isValuer {
+		if arg, err = valuer.Value(); err != nil {
+			return "", false, err
+		}
+	}
+	switch arg := arg.(type) {
+	case nil:
+		return "", false, nil
+	case time.Time:
+		return arg.UTC().Format(time.RFC3339Nano), true, nil
+	case []byte:
+		return string(arg) + cast, true, nil
+	}
+	return fmt.Sprintf("%v%s", reflect.Indirect(reflect.ValueOf(arg)), cast), true, nil
+}
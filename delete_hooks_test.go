@@ -0,0 +1,66 @@
+package orm_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/gobkc/orm"
+	"github.com/gobkc/orm/ormtest"
+)
+
+type hookedRow struct {
+	ID   int64
+	Name string
+}
+
+func (hookedRow) TableName() string { return "hooked_rows" }
+
+var hookLog []string
+
+func (r *hookedRow) BeforeDelete(ctx context.Context) error {
+	hookLog = append(hookLog, "before "+r.Name)
+	return nil
+}
+
+func (r *hookedRow) AfterDelete(ctx context.Context) error {
+	hookLog = append(hookLog, "after "+r.Name)
+	return nil
+}
+
+func TestDeleteHooksRejectStatement(t *testing.T) {
+	db, err := sql.Open("postgres", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = orm.Delete[hookedRow](context.Background(), db, "DELETE FROM hooked_rows WHERE id = $1", 1)
+	if !errors.Is(err, orm.ErrDeleteHooks) {
+		t.Fatalf("got %v, want ErrDeleteHooks", err)
+	}
+}
+
+func TestDeleteHooksRunOnRows(t *testing.T) {
+	db := ormtest.StartPostgres(t)
+	ctx := context.Background()
+	if err := orm.AutoMigrate[hookedRow](ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orm.Insert(ctx, db, []hookedRow{{Name: "a"}, {Name: "b"}, {Name: "c"}}); err != nil {
+		t.Fatal(err)
+	}
+	hookLog = nil
+	n, err := orm.Delete[hookedRow](ctx, db, "name <> $1", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || len(hookLog) != 4 || hookLog[0][:6] != "before" || hookLog[3][:5] != "after" {
+		t.Fatalf("deleted %d, hooks %q", n, hookLog)
+	}
+	for _, entry := range hookLog {
+		if entry[len(entry)-1] == 'b' {
+			t.Fatalf("hook ran on a kept row: %q", hookLog)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package orm
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// Dialect captures the SQL differences between supported databases. SQL
+// passed to the ORM is always written with Postgres style $N placeholders;
+// the dialect rewrites them before the statement is sent.
+type Dialect interface {
+	Name() string
+	// Placeholder returns the bind marker for the n-th (1-based) argument.
+	Placeholder(n int) string
+	// QuoteIdent quotes an identifier.
+	QuoteIdent(name string) string
+	// Returning reports whether INSERT ... RETURNING is available; without it
+	// generated keys are read from LastInsertId.
+	Returning() bool
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string             { return "postgres" }
+func (postgresDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+func (postgresDialect) Returning() bool          { return true }
+func (postgresDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string             { return "mysql" }
+func (mysqlDialect) Placeholder(n int) string { return "?" }
+func (mysqlDialect) Returning() bool          { return false }
+func (mysqlDialect) QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+var (
+	Postgres Dialect = postgresDialect{}
+	MySQL    Dialect = mysqlDialect{}
+)
+
+// SetDialect selects the dialect used for statements run through db. Handles
+// default to Postgres.
+func SetDialect(db *sql.DB, d Dialect) {
+	h := handleOf(db)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dialect = d
+}
+
+// DialectOf returns the dialect bound to db.
+func DialectOf(db *sql.DB) Dialect {
+	return lookupHandle(db).sqlDialect()
+}
+
+func (h *handle) sqlDialect() Dialect {
+	if h == nil {
+		return Postgres
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.dialect == nil {
+		return Postgres
+	}
+	return h.dialect
+}
+
+// rebind rewrites $N placeholders for d. Dialects with positional markers
+// get args reordered (and repeated) to follow the markers' order.
+func rebind(d Dialect, sqlStr string, args []any) (string, []any) {
+	if d.Placeholder(1) == "$1" || !strings.Contains(sqlStr, "$") {
+		return sqlStr, args
+	}
+	var b strings.Builder
+	var newArgs []any
+	pos := 0
+	for _, tok := range lexSQL(sqlStr) {
+		if tok.kind != tokParam {
+			b.WriteString(tok.text)
+			continue
+		}
+		n, err := strconv.Atoi(tok.text[1:])
+		if err != nil || n < 1 || n > len(args) {
+			b.WriteString(tok.text)
+			continue
+		}
+		pos++
+		b.WriteString(d.Placeholder(pos))
+		newArgs = append(newArgs, args[n-1])
+	}
+	return b.String(), newArgs
+}
@@ -0,0 +1,68 @@
+package orm
+
+import (
+	"reflect"
+)
+
+// RowDiff is the difference between two sets of rows.
+type RowDiff[T any] struct {
+	// Added holds rows only in b, Removed rows only in a.
+	Added   []T
+	Removed []T
+	Changed []RowChange[T]
+}
+
+// RowChange is a row present on both sides with different values.
+type RowChange[T any] struct {
+	Old, New T
+	// Columns lists the columns that differ when T is a struct.
+	Columns []string
+}
+
+// Empty reports whether the two sides matched.
+func (d RowDiff[T]) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffRows compares a (typically the table) with b (the source of truth),
+// matching rows by key. Added and Changed follow the order of b, Removed the
+// order of a. Duplicate keys are matched by their last occurrence.
+func DiffRows[T any, K comparable](a, b []T, key func(T) K) RowDiff[T] {
+	var d RowDiff[T]
+	old := make(map[K]T, len(a))
+	for _, row := range a {
+		old[key(row)] = row
+	}
+	seen := make(map[K]bool, len(b))
+	for _, row := range b {
+		k := key(row)
+		seen[k] = true
+		prev, ok := old[k]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, row)
+		case !reflect.DeepEqual(prev, row):
+			d.Changed = append(d.Changed, RowChange[T]{Old: prev, New: row, Columns: changedColumns(prev, row)})
+		}
+	}
+	for _, row := range a {
+		if !seen[key(row)] {
+			d.Removed = append(d.Removed, row)
+		}
+	}
+	return d
+}
+
+func changedColumns(a, b any) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() != reflect.Struct {
+		return nil
+	}
+	var columns []string
+	for _, f := range modelOf(nil, va.Type()).Fields {
+		if !reflect.DeepEqual(va.FieldByIndex(f.Index).Interface(), vb.FieldByIndex(f.Index).Interface()) {
+			columns = append(columns, f.Column)
+		}
+	}
+	return columns
+}
@@ -0,0 +1,164 @@
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrDSN = errors.New("invalid dsn")
+
+// SSLMode is the libpq sslmode setting.
+type SSLMode string
+
+const (
+	SSLDisable    SSLMode = "disable"
+	SSLAllow      SSLMode = "allow"
+	SSLPrefer     SSLMode = "prefer"
+	SSLRequire    SSLMode = "require"
+	SSLVerifyCA   SSLMode = "verify-ca"
+	SSLVerifyFull SSLMode = "verify-full"
+)
+
+// DSN builds a Postgres connection string.
+type DSN struct {
+	Host            string
+	Port            int
+	User            string
+	Password        string
+	Database        string
+	SSLMode         SSLMode
+	SSLRootCert     string
+	ApplicationName string
+	ConnectTimeout  time.Duration
+	// Params holds any further key=value settings, e.g. search_path.
+	Params map[string]string
+}
+
+// FromEnv fills a DSN from the standard libpq environment variables
+// (PGHOST, PGPORT, PGUSER, PGPASSWORD, PGDATABASE, PGSSLMODE, PGSSLROOTCERT,
+// PGAPPNAME, PGCONNECT_TIMEOUT).
+func FromEnv() (DSN, error) {
+	d := DSN{
+		Host:            os.Getenv("PGHOST"),
+		User:            os.Getenv("PGUSER"),
+		Password:        os.Getenv("PGPASSWORD"),
+		Database:        os.Getenv("PGDATABASE"),
+		SSLMode:         SSLMode(os.Getenv("PGSSLMODE")),
+		SSLRootCert:     os.Getenv("PGSSLROOTCERT"),
+		ApplicationName: os.Getenv("PGAPPNAME"),
+	}
+	if port := os.Getenv("PGPORT"); port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			return d, fmt.Errorf("%w: PGPORT %q", ErrDSN, port)
+		}
+		d.Port = n
+	}
+	if timeout := os.Getenv("PGCONNECT_TIMEOUT"); timeout != "" {
+		n, err := strconv.Atoi(timeout)
+		if err != nil {
+			return d, fmt.Errorf("%w: PGCONNECT_TIMEOUT %q", ErrDSN, timeout)
+		}
+		d.ConnectTimeout = time.Duration(n) * time.Second
+	}
+	return d, d.Validate()
+}
+
+// Validate checks that the required fields are present and well formed.
+func (d DSN) Validate() error {
+	switch {
+	case d.Host == "":
+		return fmt.Errorf("%w: host is required", ErrDSN)
+	case d.User == "":
+		return fmt.Errorf("%w: user is required", ErrDSN)
+	case d.Database == "":
+		return fmt.Errorf("%w: database is required", ErrDSN)
+	case d.Port < 0 || d.Port > 65535:
+		return fmt.Errorf("%w: port %d out of range", ErrDSN, d.Port)
+	}
+	switch d.SSLMode {
+	case "", SSLDisable, SSLAllow, SSLPrefer, SSLRequire, SSLVerifyCA, SSLVerifyFull:
+	default:
+		return fmt.Errorf("%w: unknown sslmode %q", ErrDSN, d.SSLMode)
+	}
+	return nil
+}
+
+// String returns the key=value form accepted by lib/pq and libpq.
+func (d DSN) String() string {
+	var parts []string
+	for _, kv := range d.pairs() {
+		parts = append(parts, kv[0]+"="+quoteDSNValue(kv[1]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// URL returns the postgres:// form of the DSN.
+func (d DSN) URL() string {
+	u := url.URL{Scheme: "postgres", Host: d.Host, Path: "/" + d.Database}
+	if d.Port != 0 {
+		u.Host += ":" + strconv.Itoa(d.Port)
+	}
+	if d.Password != "" {
+		u.User = url.UserPassword(d.User, d.Password)
+	} else if d.User != "" {
+		u.User = url.User(d.User)
+	}
+	q := url.Values{}
+	for _, kv := range d.pairs() {
+		switch kv[0] {
+		case "host", "port", "user", "password", "dbname":
+		default:
+			q.Set(kv[0], kv[1])
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (d DSN) pairs() [][2]string {
+	var out [][2]string
+	add := func(k, v string) {
+		if v != "" {
+			out = append(out, [2]string{k, v})
+		}
+	}
+	add("host", d.Host)
+	if d.Port != 0 {
+		add("port", strconv.Itoa(d.Port))
+	}
+	add("user", d.User)
+	add("password", d.Password)
+	add("dbname", d.Database)
+	add("sslmode", string(d.SSLMode))
+	add("sslrootcert", d.SSLRootCert)
+	add("application_name", d.ApplicationName)
+	if d.ConnectTimeout > 0 {
+		add("connect_timeout", strconv.Itoa(int(d.ConnectTimeout/time.Second)))
+	}
+	keys := make([]string, 0, len(d.Params))
+	for k := range d.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		add(k, d.Params[k])
+	}
+	return out
+}
+
+// quoteDSNValue quotes values containing spaces, quotes or backslashes.
+func quoteDSNValue(v string) string {
+	if !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
@@ -0,0 +1,193 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+var ErrDualWrite = errors.New("dual write: mirror failed")
+
+// DualWritePolicy decides what a failed mirror write does to the primary.
+type DualWritePolicy int
+
+const (
+	// BestEffort mirrors after the primary commits and only logs failures.
+	BestEffort DualWritePolicy = iota
+	// Strict mirrors before the primary commits and rolls the primary back
+	// when the mirror fails.
+	Strict
+)
+
+// DualWrite mirrors writes made through one handle onto a second one, e.g.
+// the new cluster or schema during a blue/green migration.
+type DualWrite struct {
+	Target *sql.DB
+	// Tables limits mirroring to these tables; empty mirrors all.
+	Tables []string
+	Policy DualWritePolicy
+	// OnError is called for BestEffort failures in addition to logging.
+	OnError func(table string, err error)
+}
+
+// EnableDualWrite mirrors Insert, Update, Delete and Merge on db to cfg.Target.
+// Raw Exec calls are not mirrored. Rows keep the ids assigned by db. Writes
+// inside a Transaction are mirrored when it commits; writes inside a
+// transaction bound with Use fail with ErrDualWrite, since the target could
+// not follow a later rollback.
+func EnableDualWrite(db *sql.DB, cfg DualWrite) {
+	h := handleOf(db)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dualWrite = &cfg
+}
+
+// DisableDualWrite stops mirroring writes made through db.
+func DisableDualWrite(db *sql.DB) {
+	if h := lookupHandle(db); h != nil {
+		h.mu.Lock()
+		h.dualWrite = nil
+		h.mu.Unlock()
+	}
+}
+
+type mirrorStmt struct {
+	sql  string
+	args []any
+}
+
+// mirror collects the statements of one write for replay on the target.
+type mirror struct {
+	cfg   *DualWrite
+	table string
+	stmts []mirrorStmt
+}
+
+// mirrorOf returns a collector when writes to table are mirrored, else nil.
+func mirrorOf(h *handle, table string) *mirror {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	cfg := h.dualWrite
+	h.mu.RUnlock()
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.Tables) > 0 {
+		found := false
+		for _, t := range cfg.Tables {
+			found = found || t == table
+		}
+		if !found {
+			return nil
+		}
+	}
+	return &mirror{cfg: cfg, table: table}
+}
+
+func (m *mirror) add(sqlStr string, args ...any) {
+	if m != nil {
+		m.stmts = append(m.stmts, mirrorStmt{sql: sqlStr, args: args})
+	}
+}
+
+// commit wraps the commit of the transaction the write ran in on db with
+// the mirror replay. Callers roll the primary back when it returns an error.
+// Inside a Transaction the statements wait for its outermost commit instead,
+// as releasing a savepoint commits nothing; inside any other transaction
+// they cannot be mirrored safely and the write fails.
+func (m *mirror) commit(ctx context.Context, db Executor, commit func() error) error {
+	if m == nil || len(m.stmts) == 0 {
+		return commit()
+	}
+	if _, inTx := rawExecutor(db).(*sql.Tx); inTx {
+		b, ok := db.(*boundExecutor)
+		if !ok || b.mirrors == nil {
+			return fmt.Errorf("%w: %s: writes inside a transaction not started by Transaction are not mirrored", ErrDualWrite, m.table)
+		}
+		if err := commit(); err != nil {
+			return err
+		}
+		b.mirrors.add(m)
+		return nil
+	}
+	q := mirrorQueue{mirrors: []*mirror{m}}
+	return q.commit(ctx, commit)
+}
+
+// mirrorQueue holds the mirrors of the writes made in a Transaction until
+// it commits.
+type mirrorQueue struct {
+	mu      sync.Mutex
+	mirrors []*mirror
+}
+
+func (q *mirrorQueue) add(mirrors ...*mirror) {
+	q.mu.Lock()
+	q.mirrors = append(q.mirrors, mirrors...)
+	q.mu.Unlock()
+}
+
+// commit replays Strict mirrors before commit and BestEffort ones after it.
+func (q *mirrorQueue) commit(ctx context.Context, commit func() error) error {
+	for _, m := range q.mirrors {
+		if m.cfg.Policy != Strict {
+			continue
+		}
+		if err := m.replay(ctx); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrDualWrite, m.table, err)
+		}
+	}
+	if err := commit(); err != nil {
+		return err
+	}
+	for _, m := range q.mirrors {
+		if m.cfg.Policy == Strict {
+			continue
+		}
+		if err := m.replay(ctx); err != nil {
+			log.Printf("[ORM ERROR]\t dual write %s: %v\n", m.table, err)
+			if m.cfg.OnError != nil {
+				m.cfg.OnError(m.table, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *mirror) replay(ctx context.Context) error {
+	tx, err := m.cfg.Target.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, s := range m.stmts {
+		if _, err = tx.ExecContext(ctx, s.sql, s.args...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// exec runs a single statement on db in a transaction, mirrored.
+func (m *mirror) exec(ctx context.Context, db Executor, sqlStr string, args ...any) (sql.Result, error) {
+	tx, err := beginTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	result, err := tx.ExecContext(ctx, sqlStr, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	m.add(sqlStr, args...)
+	if err = m.commit(ctx, db, tx.Commit); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return result, nil
+}
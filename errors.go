@@ -0,0 +1,50 @@
+package orm
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotFound is returned when a single row query, Query into a struct or
+// scalar or First, matches nothing. errors.Is matches it against
+// sql.ErrNoRows as well.
+var ErrNotFound error = notFoundError{}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string { return "orm: not found" }
+
+func (notFoundError) Is(target error) bool { return target == sql.ErrNoRows }
+
+// QueryError wraps a driver error with the statement that failed and the
+// tables it touches.
+type QueryError struct {
+	SQL   string
+	Table string
+	Err   error
+}
+
+func (e *QueryError) Error() string {
+	if e.Table == "" {
+		return fmt.Sprintf("orm: %v [%s]", e.Err, e.SQL)
+	}
+	return fmt.Sprintf("orm: %s: %v [%s]", e.Table, e.Err, e.SQL)
+}
+
+func (e *QueryError) Unwrap() error { return e.Err }
+
+// wrapQueryError wraps err from running sqlStr in a QueryError; table
+// defaults to the tables named by sqlStr. ErrNotFound and errors already
+// wrapped are returned as they are.
+func wrapQueryError(err error, sqlStr, table string) error {
+	var qe *QueryError
+	if err == nil || errors.Is(err, ErrNotFound) || errors.As(err, &qe) {
+		return err
+	}
+	if table == "" {
+		table = strings.Join(statementTables(sqlStr), ", ")
+	}
+	return &QueryError{SQL: sqlStr, Table: table, Err: err}
+}
@@ -0,0 +1,187 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// Executor runs statements. *sql.DB, *sql.Conn and *sql.Tx all satisfy it.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+var (
+	_ Executor = (*sql.DB)(nil)
+	_ Executor = (*sql.Conn)(nil)
+	_ Executor = (*sql.Tx)(nil)
+)
+
+var ErrExecutor = errors.New("orm: executor cannot start a transaction")
+
+// boundExecutor carries the settings of the *sql.DB an executor belongs to.
+type boundExecutor struct {
+	Executor
+	db *sql.DB
+	// mirrors collects the dual writes of a Transaction until it commits;
+	// nil for executors bound with Use.
+	mirrors *mirrorQueue
+}
+
+// Use returns ex (typically a *sql.Tx or *sql.Conn obtained from db) bound to
+// db's settings: column mapper, dialect, dual writes and so on. Executors
+// that are not bound run with the defaults.
+func Use(db *sql.DB, ex Executor) Executor {
+	if b, ok := ex.(*boundExecutor); ok {
+		ex = b.Executor
+	}
+	return &boundExecutor{Executor: ex, db: db}
+}
+
+// handleFor returns the handle settings for ex, or nil for the defaults.
+func handleFor(ex Executor) *handle {
+	switch e := ex.(type) {
+	case *sql.DB:
+		return lookupHandle(e)
+	case *boundExecutor:
+		return lookupHandle(e.db)
+	}
+	return nil
+}
+
+// rawExecutor strips any binding from ex.
+func rawExecutor(ex Executor) Executor {
+	if b, ok := ex.(*boundExecutor); ok {
+		return b.Executor
+	}
+	return ex
+}
+
+// execTx is a transaction started by the ORM: a real one on a *sql.DB or
+// *sql.Conn, or a savepoint when the caller already holds a *sql.Tx, so the
+// ORM's own all-or-nothing writes compose with caller managed transactions.
+type execTx struct {
+	Executor
+	commit   func() error
+	rollback func() error
+}
+
+func (t *execTx) Commit() error   { return t.commit() }
+func (t *execTx) Rollback() error { return t.rollback() }
+
+var savepointSeq int64
+
+// beginTx starts a transaction, or a savepoint inside a *sql.Tx, on ex and
+// applies the session vars of its handle.
+func beginTx(ctx context.Context, ex Executor) (*execTx, error) {
+	t, err := startTx(ctx, ex)
+	if err != nil {
+		return nil, err
+	}
+	h := handleFor(ex)
+	if settings := h.sessionSettings(ctx); len(settings) > 0 {
+		if err = applySettings(ctx, h, t, settings); err != nil {
+			t.Rollback()
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func startTx(ctx context.Context, ex Executor) (*execTx, error) {
+	switch e := rawExecutor(ex).(type) {
+	case *sql.Tx:
+		name := fmt.Sprintf("orm_sp_%d", atomic.AddInt64(&savepointSeq, 1))
+		if _, err := e.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+			return nil, err
+		}
+		return &execTx{
+			Executor: e,
+			commit: func() error {
+				_, err := e.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+				return err
+			},
+			rollback: func() error {
+				// Rolling back to a savepoint keeps it open; release it so
+				// long batches do not pile savepoints up in the transaction.
+				if _, err := e.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); err != nil {
+					return err
+				}
+				_, err := e.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+				return err
+			},
+		}, nil
+	case interface {
+		BeginTx(context.Context, *sql.TxOptions) (*sql.Tx, error)
+	}:
+		tx, err := e.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &execTx{Executor: tx, commit: tx.Commit, rollback: tx.Rollback}, nil
+	}
+	return nil, fmt.Errorf("%w: %T", ErrExecutor, ex)
+}
+
+// Transaction runs fn in a transaction on db, committing when fn returns nil
+// and rolling back when it returns an error, panics or exits the goroutine.
+// The Executor passed to fn keeps db's settings. When db is already a
+// transaction, fn runs inside a savepoint instead, so helpers using
+// Transaction compose.
+func Transaction(ctx context.Context, db Executor, fn func(tx Executor) error) (err error) {
+	tx, err := beginTx(ctx, db)
+	if err != nil {
+		return err
+	}
+	returned := false
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if !returned {
+			// fn called runtime.Goexit, e.g. through t.FailNow in a test.
+			tx.Rollback()
+		}
+	}()
+	var ex Executor = tx.Executor
+	queue := &mirrorQueue{}
+	switch owner := db.(type) {
+	case *sql.DB:
+		ex = &boundExecutor{Executor: ex, db: owner, mirrors: queue}
+	case *boundExecutor:
+		ex = &boundExecutor{Executor: ex, db: owner.db, mirrors: queue}
+	}
+	err = fn(ex)
+	returned = true
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(queue.mirrors) == 0 {
+		return tx.Commit()
+	}
+	// Dual writes are replayed when the outermost Transaction commits.
+	if _, nested := rawExecutor(db).(*sql.Tx); nested {
+		parent, ok := db.(*boundExecutor)
+		if !ok || parent.mirrors == nil {
+			tx.Rollback()
+			return fmt.Errorf("%w: writes inside a transaction not started by Transaction are not mirrored", ErrDualWrite)
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+		parent.mirrors.add(queue.mirrors...)
+		return nil
+	}
+	if err = queue.commit(ctx, tx.Commit); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// maxBindParams keeps statements below the 65535 parameter limit of the
+// Postgres protocol.
+const maxBindParams = 10000
+
+// ExistingKeys reports which keys already exist in column of T's table. Every
+// key is present in the result; keys are bound as parameters and queried in
+// chunks of at most 10000.
+func ExistingKeys[T any, K comparable](ctx context.Context, db Executor, column string, keys []K) (map[K]bool, error) {
+	db = route[T](db)
+	h := handleFor(db)
+	f, ok := modelOf(h, reflect.TypeOf(new(T)).Elem()).selectable(column)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown field %q", ErrFilter, column)
+	}
+	found := make(map[K]bool, len(keys))
+	for _, k := range keys {
+		found[k] = false
+	}
+	table := applyOptions(nil).tableName(h, new(T))
+	for start := 0; start < len(keys); start += maxBindParams {
+		end := start + maxBindParams
+		if end > len(keys) {
+			end = len(keys)
+		}
+		chunk := keys[start:end]
+		holders := make([]string, len(chunk))
+		args := make([]any, len(chunk))
+		for i, k := range chunk {
+			holders[i] = fmt.Sprintf("$%d", i+1)
+			args[i] = k
+		}
+		sqlStr := fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s IN (%s)", f.Column, table, f.Column, strings.Join(holders, ","))
+		if err := scanExisting(ctx, db, h, sqlStr, args, found); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+func scanExisting[K comparable](ctx context.Context, db Executor, h *handle, sqlStr string, args []any, found map[K]bool) error {
+	defer outputTimed(sqlStr, args, time.Now())
+	query, queryArgs := rebind(h.sqlDialect(), sqlStr, args)
+	rows, err := db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return wrapQueryError(err, sqlStr, "")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var k K
+		if err = rows.Scan(&k); err != nil {
+			return err
+		}
+		found[k] = true
+	}
+	return rows.Err()
+}
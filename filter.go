@@ -0,0 +1,142 @@
+package orm
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrFilter = errors.New("filter: invalid expression")
+
+var filterOps = map[string]string{
+	"eq":    "=",
+	"ne":    "<>",
+	"gt":    ">",
+	"gte":   ">=",
+	"lt":    "<",
+	"lte":   "<=",
+	"like":  "LIKE",
+	"ilike": "ILIKE",
+	"in":    "IN",
+	"null":  "IS NULL",
+}
+
+// ParseFilter parses an API filter string such as
+// "status=eq:active&age=gte:18&name=like:jo*" into a parameterized condition
+// for T. See FilterValues for the syntax.
+func ParseFilter[T any](db *sql.DB, query string) (where string, args []any, err error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrFilter, err)
+	}
+	return FilterValues[T](db, values)
+}
+
+// FilterValues turns field=op:value pairs into "col op $n" conditions joined by
+// AND. Fields are matched against T like SelectionColumns and values are
+// converted to the field type. Supported ops are eq (default), ne, gt, gte,
+// lt, lte, like and ilike (* is a wildcard), in (comma separated) and
+// null:true / null:false.
+func FilterValues[T any](db *sql.DB, values url.Values) (where string, args []any, err error) {
+	m := modelOf(lookupHandle(db), reflect.TypeOf(new(T)).Elem())
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var conds []string
+	for _, key := range keys {
+		f, ok := m.selectable(key)
+		if !ok {
+			return "", nil, fmt.Errorf("%w: unknown field %q", ErrFilter, key)
+		}
+		for _, expr := range values[key] {
+			cond, condArgs, err := filterCond(f, expr, len(args))
+			if err != nil {
+				return "", nil, err
+			}
+			conds = append(conds, cond)
+			args = append(args, condArgs...)
+		}
+	}
+	return strings.Join(conds, " AND "), args, nil
+}
+
+func filterCond(f *field, expr string, offset int) (string, []any, error) {
+	op, raw := "eq", expr
+	if i := strings.IndexByte(expr, ':'); i > 0 {
+		if _, ok := filterOps[expr[:i]]; ok {
+			op, raw = expr[:i], expr[i+1:]
+		}
+	}
+	switch op {
+	case "null":
+		isNull, err := strconv.ParseBool(raw)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: %s: %v", ErrFilter, f.Column, err)
+		}
+		if isNull {
+			return f.Column + " IS NULL", nil, nil
+		}
+		return f.Column + " IS NOT NULL", nil, nil
+	case "in":
+		parts := strings.Split(raw, ",")
+		holders := make([]string, len(parts))
+		args := make([]any, len(parts))
+		for i, part := range parts {
+			v, err := filterValue(f, part)
+			if err != nil {
+				return "", nil, err
+			}
+			args[i] = v
+			holders[i] = fmt.Sprintf("$%d", offset+i+1)
+		}
+		return fmt.Sprintf("%s IN (%s)", f.Column, strings.Join(holders, ",")), args, nil
+	case "like", "ilike":
+		return fmt.Sprintf("%s %s $%d", f.Column, filterOps[op], offset+1), []any{strings.ReplaceAll(raw, "*", "%")}, nil
+	}
+	v, err := filterValue(f, raw)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("%s %s $%d", f.Column, filterOps[op], offset+1), []any{v}, nil
+}
+
+func filterValue(f *field, raw string) (any, error) {
+	t := f.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var (
+		v   any
+		err error
+	)
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err = strconv.ParseInt(raw, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err = strconv.ParseUint(raw, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		v, err = strconv.ParseFloat(raw, 64)
+	case reflect.Bool:
+		v, err = strconv.ParseBool(raw)
+	case reflect.Struct:
+		if t == reflect.TypeOf(time.Time{}) {
+			v, err = time.Parse(time.RFC3339, raw)
+		} else {
+			v = raw
+		}
+	default:
+		v = raw
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrFilter, f.Column, err)
+	}
+	return v, nil
+}
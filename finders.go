@@ -0,0 +1,163 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Find returns the rows of T's table matching where (e.g. "status = $1");
+// an empty where selects every row. Like First, Count and Paginate it
+// leaves out soft deleted rows unless args hold Unscoped.
+func Find[T any](ctx context.Context, db Executor, where string, args ...any) ([]T, error) {
+	rows, err := Query[[]T](ctx, db, selectSql[T](db, "*", where, args), args...)
+	if err != nil {
+		return nil, err
+	}
+	return *rows, nil
+}
+
+// First returns the first row of T's table matching where, or ErrNotFound
+// when nothing matched.
+func First[T any](ctx context.Context, db Executor, where string, args ...any) (*T, error) {
+	rows, err := Query[[]T](ctx, db, selectSql[T](db, "*", where, args)+" LIMIT 1", args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(*rows) == 0 {
+		return nil, ErrNotFound
+	}
+	return &(*rows)[0], nil
+}
+
+// Count returns the number of rows of T's table matching where.
+func Count[T any](ctx context.Context, db Executor, where string, args ...any) (int64, error) {
+	n, err := Query[int64](ctx, db, selectSql[T](db, "COUNT(*)", where, args), args...)
+	if err != nil {
+		return 0, err
+	}
+	return *n, nil
+}
+
+// selectSql builds the SELECT of the helpers above; soft deleted rows are
+// excluded unless args hold Unscoped.
+func selectSql[T any](db Executor, columns, where string, args []any) string {
+	_, opts := splitOptions(args)
+	h := handleFor(route[T](db))
+	sqlStr := fmt.Sprintf("SELECT %s FROM %s", columns, opts.tableName(h, new(T)))
+	if where = scopedWhere[T](h, where, opts); where != "" {
+		sqlStr += " WHERE " + where
+	}
+	return sqlStr
+}
+
+// Page is one page of rows with the totals of the whole result.
+type Page[T any] struct {
+	Rows       []T   `json:"rows"`
+	Page       int   `json:"page"`
+	PerPage    int   `json:"per_page"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// Paginate returns page (starting at 1) of the rows matching where, ordered
+// by primary key, along with the total row and page counts. perPage defaults
+// to and is capped at MaxPageSize.
+func Paginate[T any](ctx context.Context, db Executor, page, perPage int, where string, args ...any) (*Page[T], error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage <= 0 || perPage > MaxPageSize {
+		perPage = MaxPageSize
+	}
+	total, err := Count[T](ctx, db, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	sqlStr := selectSql[T](db, "*", where, args)
+	var order []string
+	for _, f := range modelOf(handleFor(route[T](db)), reflect.TypeOf(new(T)).Elem()).primaryKey() {
+		order = append(order, f.Column)
+	}
+	if len(order) > 0 {
+		sqlStr += " ORDER BY " + strings.Join(order, ", ")
+	}
+	sqlStr += fmt.Sprintf(" LIMIT %d OFFSET %d", perPage, (page-1)*perPage)
+	rows, err := Query[[]T](ctx, db, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &Page[T]{
+		Rows:       *rows,
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: int((total + int64(perPage) - 1) / int64(perPage)),
+	}, nil
+}
+
+// FirstOrCreate returns the row matching the non-zero fields of probe, or
+// inserts probe with its zero fields taken from defaults and returns the
+// stored row; created tells which happened. The row is written like Insert
+// writes it, in its own transaction or savepoint. When a concurrent call wins
+// the race, the unique violation is caught and the row it created is
+// returned; that needs a unique index over the probed columns.
+func FirstOrCreate[T any](ctx context.Context, db Executor, probe T, defaults T) (row *T, created bool, err error) {
+	db = route[T](db)
+	h := handleFor(db)
+	m := modelOf(h, reflect.TypeOf(new(T)).Elem())
+	pv := reflect.ValueOf(probe)
+	var conds []string
+	var args []any
+	for _, sf := range columnFields(pv.Type()) {
+		fv := pv.FieldByIndex(sf.Index)
+		if sf.Tag.Get("generated") != "" || fv.IsZero() {
+			continue
+		}
+		arg, cast, skip, err := bindValue(sf, fv)
+		if err != nil {
+			return nil, false, err
+		}
+		if skip {
+			continue
+		}
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf("%s = $%d%s", columnOf(h, sf), len(args), cast))
+	}
+	if len(conds) == 0 {
+		return nil, false, fmt.Errorf("%w: FirstOrCreate probe has no fields set", ErrFilter)
+	}
+	where := strings.Join(conds, " AND ")
+	if row, err = First[T](ctx, db, where, args...); !errors.Is(err, ErrNotFound) {
+		return row, false, err
+	}
+
+	insert := probe
+	iv, dv := reflect.ValueOf(&insert).Elem(), reflect.ValueOf(defaults)
+	for _, f := range m.Fields {
+		if fv := iv.FieldByIndex(f.Index); fv.IsZero() {
+			fv.Set(dv.FieldByIndex(f.Index))
+		}
+	}
+	table := applyOptions(nil).tableName(h, new(T))
+	mirror := mirrorOf(h, table)
+	tx, err := beginTx(ctx, db)
+	if err != nil {
+		return nil, false, err
+	}
+	if err = insertRow(ctx, tx, h, table, mirror, &insert); err != nil {
+		tx.Rollback()
+		if !IsUniqueViolation(err) {
+			return nil, false, err
+		}
+		row, err = First[T](ctx, db, where, args...)
+		return row, false, err
+	}
+	if err = mirror.commit(ctx, db, tx.Commit); err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+	return &insert, true, nil
+}
@@ -0,0 +1,173 @@
+package orm
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QueryFingerprint aggregates executions of statements that only differ in
+// their literal values.
+type QueryFingerprint struct {
+	Fingerprint string
+	Tables      []string
+	Count       int64
+	LastSeen    time.Time
+}
+
+var (
+	fingerprintMu sync.Mutex
+	fingerprints  = make(map[string]*QueryFingerprint)
+	// MaxFingerprints bounds the number of distinct statements remembered.
+	MaxFingerprints = 1000
+)
+
+// Fingerprint normalizes a statement: literals and parameters become ?,
+// IN lists collapse to IN (?), comments are dropped, keywords are upper cased
+// and whitespace is collapsed.
+func Fingerprint(sqlStr string) string {
+	var b strings.Builder
+	tokens := lexSQL(sqlStr)
+	space := false
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		text := tok.text
+		switch tok.kind {
+		case tokSpace, tokComment:
+			space = b.Len() > 0
+			continue
+		case tokString, tokDollarString, tokNumber, tokParam:
+			text = "?"
+		case tokWord:
+			if isKeyword(text) {
+				text = strings.ToUpper(text)
+			}
+			if text == "IN" {
+				if j, ok := skipInList(tokens, i+1); ok {
+					if space {
+						b.WriteByte(' ')
+					}
+					b.WriteString("IN (?)")
+					i, space = j, false
+					continue
+				}
+			}
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+// skipInList returns the index of the closing parenthesis of a literal list.
+func skipInList(tokens []token, i int) (int, bool) {
+	for i < len(tokens) && tokens[i].kind == tokSpace {
+		i++
+	}
+	if i >= len(tokens) || tokens[i].text != "(" {
+		return 0, false
+	}
+	for i++; i < len(tokens); i++ {
+		switch tokens[i].kind {
+		case tokSpace, tokString, tokNumber, tokParam:
+			continue
+		}
+		switch tokens[i].text {
+		case ",":
+			continue
+		case ")":
+			return i, true
+		}
+		return 0, false
+	}
+	return 0, false
+}
+
+// statementTables returns the tables referenced after FROM, JOIN, UPDATE and
+// INTO, without their schema.
+func statementTables(sqlStr string) []string {
+	var (
+		tables     []string
+		seen       = make(map[string]bool)
+		name       string
+		collecting bool
+		dot        bool
+	)
+	flush := func() {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			tables = append(tables, name)
+		}
+		name, collecting, dot = "", false, false
+	}
+	for _, tok := range lexSQL(sqlStr) {
+		if tok.kind == tokSpace || tok.kind == tokComment {
+			continue
+		}
+		if collecting {
+			isIdent := tok.kind == tokWord || tok.kind == tokQuotedIdent
+			if isIdent && (name == "" || dot) {
+				name, dot = strings.Trim(tok.text, `"`), false
+				continue
+			}
+			if tok.text == "." && name != "" {
+				dot = true
+				continue
+			}
+			flush()
+		}
+		if tok.kind == tokWord {
+			switch strings.ToUpper(tok.text) {
+			case "FROM", "JOIN", "UPDATE", "INTO":
+				collecting = true
+			}
+		}
+	}
+	flush()
+	return tables
+}
+
+func recordFingerprint(sqlStr string) {
+	fp := Fingerprint(sqlStr)
+	now := time.Now()
+	fingerprintMu.Lock()
+	defer fingerprintMu.Unlock()
+	if f, ok := fingerprints[fp]; ok {
+		f.Count++
+		f.LastSeen = now
+		return
+	}
+	if len(fingerprints) >= MaxFingerprints {
+		return
+	}
+	fingerprints[fp] = &QueryFingerprint{Fingerprint: fp, Tables: statementTables(sqlStr), Count: 1, LastSeen: now}
+}
+
+// QueryFingerprints returns the statements issued through the ORM since the
+// last reset, most frequent first.
+func QueryFingerprints() []QueryFingerprint {
+	fingerprintMu.Lock()
+	list := make([]QueryFingerprint, 0, len(fingerprints))
+	for _, f := range fingerprints {
+		list = append(list, *f)
+	}
+	fingerprintMu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Fingerprint < list[j].Fingerprint
+	})
+	return list
+}
+
+// ResetFingerprints forgets every recorded statement.
+func ResetFingerprints() {
+	fingerprintMu.Lock()
+	defer fingerprintMu.Unlock()
+	fingerprints = make(map[string]*QueryFingerprint)
+}
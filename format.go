@@ -0,0 +1,175 @@
+package orm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// comparison operators are always surrounded by single spaces.
+var comparisonOps = map[string]bool{"=": true, "<": true, ">": true, "<=": true, ">=": true, "<>": true, "!=": true}
+
+// clauseStarts begin a new line in PrettySQL (at the outermost level).
+var clauseStarts = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "HAVING": true, "ORDER": true,
+	"LIMIT": true, "OFFSET": true, "SET": true, "VALUES": true, "RETURNING": true, "UNION": true,
+	"INTERSECT": true, "EXCEPT": true, "JOIN": true, "LEFT": true, "RIGHT": true, "INNER": true,
+	"FULL": true, "CROSS": true, "USING": true, "WHEN": true,
+}
+
+// fmtToken is a lexed token prepared for formatting.
+type fmtToken struct {
+	text  string
+	kind  tokenKind
+	depth int
+	// glued means no whitespace separated it from the previous token.
+	glued bool
+}
+
+// formatTokens drops comments and whitespace, upper-cases keywords and
+// merges multi-character operators.
+func formatTokens(s string) []fmtToken {
+	var out []fmtToken
+	depth, glued := 0, false
+	for _, tok := range lexSQL(s) {
+		switch tok.kind {
+		case tokSpace, tokComment:
+			glued = false
+			continue
+		case tokWord:
+			if isKeyword(tok.text) {
+				tok.text = strings.ToUpper(tok.text)
+			}
+		case tokPunct:
+			if tok.text == ")" {
+				depth--
+			}
+			if n := len(out); n > 0 && glued && isOperator(tok.text) && isOperator(out[n-1].text) {
+				out[n-1].text += tok.text
+				continue
+			}
+		}
+		out = append(out, fmtToken{text: tok.text, kind: tok.kind, depth: depth, glued: glued && len(out) > 0})
+		if tok.text == "(" {
+			depth++
+		}
+		glued = true
+	}
+	return out
+}
+
+func isOperator(s string) bool {
+	return s != "" && strings.Trim(s, "<>=!~+-*/%|&^#@:") == ""
+}
+
+// spaceBefore decides the separator between prev and cur.
+func spaceBefore(prev, cur fmtToken) bool {
+	switch {
+	case cur.text == "," || cur.text == ")" || cur.text == ";" || cur.text == "]":
+		return false
+	case prev.text == "(" || prev.text == "[":
+		return false
+	case prev.text == ",":
+		return true
+	case prev.text == "." || cur.text == "." || prev.text == "::" || cur.text == "::":
+		return false
+	case comparisonOps[prev.text] || comparisonOps[cur.text]:
+		return true
+	case cur.text == "(" && (prev.kind == tokWord && !isKeyword(prev.text) || prev.kind == tokQuotedIdent):
+		return false
+	case cur.glued && (isOperator(prev.text) || isOperator(cur.text)):
+		return false
+	}
+	return true
+}
+
+// FormatSQL normalizes a statement onto one line: comments are removed,
+// keywords upper-cased and whitespace reduced to single spaces placed
+// consistently around punctuation. String literals are left untouched. The
+// same statement written with different spacing formats identically, which
+// keeps logs and golden files stable.
+func FormatSQL(s string) string {
+	var b strings.Builder
+	tokens := formatTokens(s)
+	for i, tok := range tokens {
+		if i > 0 && spaceBefore(tokens[i-1], tok) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(tok.text)
+	}
+	return b.String()
+}
+
+// PrettySQL formats a statement like FormatSQL but starts each top-level
+// clause on its own line and puts AND/OR conditions of WHERE on indented
+// lines.
+func PrettySQL(s string) string {
+	var b strings.Builder
+	tokens := formatTokens(s)
+	for i, tok := range tokens {
+		if i > 0 {
+			prev := tokens[i-1]
+			switch {
+			case tok.depth == 0 && tok.kind == tokWord && breaksClause(tokens, i):
+				b.WriteByte('\n')
+			case tok.depth == 0 && (tok.text == "AND" || tok.text == "OR") && !inBetween(tokens, i):
+				b.WriteString("\n  ")
+			case spaceBefore(prev, tok):
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(tok.text)
+	}
+	return b.String()
+}
+
+func breaksClause(tokens []fmtToken, i int) bool {
+	word, prev := tokens[i].text, tokens[i-1].text
+	switch {
+	case word == "ON":
+		return i+1 < len(tokens) && tokens[i+1].text == "CONFLICT"
+	case word == "JOIN":
+		return !(prev == "LEFT" || prev == "RIGHT" || prev == "INNER" || prev == "FULL" || prev == "CROSS" || prev == "OUTER")
+	case word == "SET":
+		return !(prev == "UPDATE" && i >= 2 && tokens[i-2].text == "DO")
+	case word == "USING" || word == "WHEN":
+		return prev != "(" && clauseStarts[word] && hasWord(tokens[:i], "MERGE")
+	case word == "SELECT":
+		return prev != "UNION" && prev != "ALL" && prev != "INTERSECT" && prev != "EXCEPT"
+	}
+	return clauseStarts[word]
+}
+
+func hasWord(tokens []fmtToken, word string) bool {
+	for _, t := range tokens {
+		if t.text == word {
+			return true
+		}
+	}
+	return false
+}
+
+// inBetween reports whether the AND at i belongs to BETWEEN x AND y.
+func inBetween(tokens []fmtToken, i int) bool {
+	if tokens[i].text != "AND" {
+		return false
+	}
+	for j := i - 1; j >= 0 && j >= i-4; j-- {
+		switch tokens[j].text {
+		case "BETWEEN":
+			return true
+		case "AND", "OR", "WHERE":
+			return false
+		}
+	}
+	return false
+}
+
+// logPrefix matches the prefix of lines written by the ORM's query log.
+var logPrefix = regexp.MustCompile(`^(?:\d{4}/\d\d/\d\d \d\d:\d\d:\d\d(?:\.\d+)? )?\[ORM [A-Z]+\]\s*`)
+
+// ParseSQL pretty-prints a statement copied from the query log. The log
+// timestamp and [ORM INFO] prefix are stripped when present, so both raw log
+// lines and bare statements are accepted.
+func ParseSQL(line string) string {
+	return PrettySQL(logPrefix.ReplaceAllString(strings.TrimSpace(line), ""))
+}
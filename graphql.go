@@ -0,0 +1,64 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SelectionColumns resolves the field names of a GraphQL selection set to the
+// columns of T. Names may be the Go field name, its lowerCamel form or the
+// column itself. Unknown names (relations, __typename) are ignored and primary
+// keys are always selected so nested resolvers can load children.
+func SelectionColumns[T any](db *sql.DB, selection []string) []string {
+	m := modelOf(lookupHandle(db), reflect.TypeOf(new(T)).Elem())
+	seen := make(map[string]bool)
+	var columns []string
+	add := func(f *field) {
+		if !seen[f.Column] {
+			seen[f.Column] = true
+			columns = append(columns, f.Column)
+		}
+	}
+	for _, f := range m.Fields {
+		if f.Primary {
+			add(f)
+		}
+	}
+	for _, name := range selection {
+		if f, ok := m.selectable(name); ok {
+			add(f)
+		}
+	}
+	return columns
+}
+
+// ResolveSelection loads the rows of T matching where, selecting only the
+// columns requested by a GraphQL selection set. where may be empty.
+func ResolveSelection[T any](ctx context.Context, db *sql.DB, selection []string, where string, args ...any) ([]T, error) {
+	columns := SelectionColumns[T](db, selection)
+	_, opts := splitOptions(args)
+	sqlStr := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ","), opts.tableName(lookupHandle(db), new(T)))
+	if where != "" {
+		sqlStr += " WHERE " + where
+	}
+	rows, err := Query[[]T](ctx, db, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	return *rows, nil
+}
+
+func (m *model) selectable(name string) (*field, bool) {
+	if f, ok := m.byColumn[name]; ok {
+		return f, true
+	}
+	for _, f := range m.Fields {
+		if strings.EqualFold(f.Name, name) || strings.EqualFold(f.Column, toSnake(name)) {
+			return f, true
+		}
+	}
+	return nil, false
+}
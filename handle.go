@@ -0,0 +1,103 @@
+package orm
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// handle holds settings bound to a single *sql.DB.
+type handle struct {
+	mu          sync.RWMutex
+	mapper      ColumnMapper
+	server      *Server
+	version     *Server // version only, for feature gates
+	dualWrite   *DualWrite
+	shadow      *ShadowRead
+	hedge       *Hedge
+	clock       Clock
+	sessionVars []sessionVar
+	dialect     Dialect
+	parallel    chan struct{}
+}
+
+var handles sync.Map
+
+func handleOf(db *sql.DB) *handle {
+	if db == nil {
+		return nil
+	}
+	if h, ok := handles.Load(db); ok {
+		return h.(*handle)
+	}
+	h, _ := handles.LoadOrStore(db, &handle{})
+	return h.(*handle)
+}
+
+func lookupHandle(db *sql.DB) *handle {
+	if db == nil {
+		return nil
+	}
+	if h, ok := handles.Load(db); ok {
+		return h.(*handle)
+	}
+	return nil
+}
+
+func (h *handle) setMapper(m ColumnMapper) {
+	h.mu.Lock()
+	h.mapper = m
+	h.mu.Unlock()
+	resetModels()
+}
+
+func (h *handle) columnMapper() ColumnMapper {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.mapper
+}
+
+func (h *handle) cachedServer() *Server {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.server
+}
+
+func (h *handle) setServer(s *Server) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.server = s
+}
+
+// cachedVersion returns the server version known to h, from ServerInfo or
+// an earlier feature gate.
+func (h *handle) cachedVersion() *Server {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.server != nil {
+		return h.server
+	}
+	return h.version
+}
+
+func (h *handle) setVersion(s *Server) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.version = s
+}
+
+func resetServers() {
+	handles.Range(func(_, h any) bool {
+		h.(*handle).setServer(nil)
+		h.(*handle).setVersion(nil)
+		return true
+	})
+}
@@ -0,0 +1,100 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Hedge sends a duplicate of slow reads to a replica to cut tail latency.
+type Hedge struct {
+	Replica *sql.DB
+	// Delay is how long a read may run on the primary before the replica
+	// is asked too; it defaults to 50ms. Tune it near the p95 latency.
+	Delay time.Duration
+}
+
+// EnableHedging lets reads on db made with the Hedged option race a copy on
+// cfg.Replica once cfg.Delay has passed; the first result wins and the
+// other read is cancelled.
+func EnableHedging(db *sql.DB, cfg Hedge) {
+	if cfg.Delay <= 0 {
+		cfg.Delay = 50 * time.Millisecond
+	}
+	h := handleOf(db)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hedge = &cfg
+}
+
+// DisableHedging stops hedging reads made through db.
+func DisableHedging(db *sql.DB) {
+	if h := lookupHandle(db); h != nil {
+		h.mu.Lock()
+		h.hedge = nil
+		h.mu.Unlock()
+	}
+}
+
+// Hedged marks a latency sensitive read for hedging on a handle set up with
+// EnableHedging. Reads inside transactions or with planner settings are
+// never hedged, as the replica cannot see their session.
+func Hedged() QueryOption {
+	return queryOptionFunc(func(o *queryOptions) {
+		o.hedged = true
+	})
+}
+
+// hedging returns the hedge config for a read on ex, nil when the read is
+// not hedged.
+func (o *queryOptions) hedging(h *handle, ex Executor) *Hedge {
+	if !o.hedged || h == nil {
+		return nil
+	}
+	if _, ok := rawExecutor(ex).(*sql.DB); !ok {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.hedge
+}
+
+// hedgedRead runs read on primary and, if it has not finished after the
+// hedge delay, on the replica too, returning the first success. A failure
+// waits for the other read when one is running.
+func hedgedRead[T any](ctx context.Context, primary Executor, hedge *Hedge, read func(context.Context, Executor, *T) error) (*T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		t   *T
+		err error
+	}
+	results := make(chan result, 2)
+	run := func(ex Executor) {
+		t := new(T)
+		err := read(ctx, ex, t)
+		results <- result{t, err}
+	}
+	go run(primary)
+	timer := time.NewTimer(hedge.Delay)
+	defer timer.Stop()
+	pending := 1
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			pending++
+			go run(hedge.Replica)
+		case r := <-results:
+			if r.err == nil {
+				return r.t, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if pending--; pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package orm
+
+import "context"
+
+// Models implement these to run logic around Insert, Update and Delete.
+// They run per row on a pointer to it, inside the statement's transaction,
+// so an error rolls every row back. Delete works on a where clause, so for
+// models with delete hooks it reads the matching rows first.
+type (
+	BeforeInserter interface {
+		BeforeInsert(ctx context.Context) error
+	}
+	AfterInserter interface {
+		AfterInsert(ctx context.Context) error
+	}
+	BeforeUpdater interface {
+		BeforeUpdate(ctx context.Context) error
+	}
+	AfterUpdater interface {
+		AfterUpdate(ctx context.Context) error
+	}
+	BeforeDeleter interface {
+		BeforeDelete(ctx context.Context) error
+	}
+	AfterDeleter interface {
+		AfterDelete(ctx context.Context) error
+	}
+)
+
+func beforeInsert(ctx context.Context, row any) error {
+	if h, ok := row.(BeforeInserter); ok {
+		return h.BeforeInsert(ctx)
+	}
+	return nil
+}
+
+func afterInsert(ctx context.Context, row any) error {
+	if h, ok := row.(AfterInserter); ok {
+		return h.AfterInsert(ctx)
+	}
+	return nil
+}
+
+func beforeUpdate(ctx context.Context, row any) error {
+	if h, ok := row.(BeforeUpdater); ok {
+		return h.BeforeUpdate(ctx)
+	}
+	return nil
+}
+
+func afterUpdate(ctx context.Context, row any) error {
+	if h, ok := row.(AfterUpdater); ok {
+		return h.AfterUpdate(ctx)
+	}
+	return nil
+}
+
+func beforeDelete(ctx context.Context, row any) error {
+	if h, ok := row.(BeforeDeleter); ok {
+		return h.BeforeDelete(ctx)
+	}
+	return nil
+}
+
+func afterDelete(ctx context.Context, row any) error {
+	if h, ok := row.(AfterDeleter); ok {
+		return h.AfterDelete(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package orm
+
+import (
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IDGenerator produces a client side primary key. The value must be
+// convertible to the type of the tagged field.
+type IDGenerator func() any
+
+var (
+	idGenMu      sync.RWMutex
+	idGenerators = map[string]IDGenerator{
+		"ulid":      func() any { return NewULID() },
+		"ksuid":     func() any { return NewKSUID() },
+		"snowflake": func() any { return NewSnowflake() },
+		"uuid":      func() any { return NewUUID() },
+	}
+)
+
+// RegisterIDGenerator makes gen available to fields tagged pri:"<name>".
+// Insert fills such fields when they are zero and writes them explicitly
+// instead of relying on RETURNING.
+func RegisterIDGenerator(name string, gen IDGenerator) {
+	idGenMu.Lock()
+	defer idGenMu.Unlock()
+	idGenerators[name] = gen
+}
+
+func idGenerator(tag reflect.StructTag) (IDGenerator, bool) {
+	name := tag.Get("pri")
+	if name == "" {
+		return nil, false
+	}
+	idGenMu.RLock()
+	defer idGenMu.RUnlock()
+	gen, ok := idGenerators[name]
+	return gen, ok
+}
+
+// assignIDs fills zero client generated keys of the struct pointed to by
+// dest and reports whether the model uses client generated keys at all.
+func assignIDs(h *handle, dest any) (bool, error) {
+	v := reflect.ValueOf(dest).Elem()
+	generated := false
+	for _, f := range modelOf(h, v.Type()).Fields {
+		gen, ok := idGenerator(f.Tag)
+		if !ok {
+			continue
+		}
+		generated = true
+		fv := v.FieldByIndex(f.Index)
+		if !fv.IsZero() {
+			continue
+		}
+		id := reflect.ValueOf(gen())
+		if s, ok := id.Interface().(fmt.Stringer); ok && fv.Kind() == reflect.String && !id.Type().ConvertibleTo(fv.Type()) {
+			id = reflect.ValueOf(s.String())
+		}
+		if !id.Type().ConvertibleTo(fv.Type()) {
+			return generated, fmt.Errorf("orm: %s id cannot be stored in %s (%s)", f.Tag.Get("pri"), f.Name, fv.Type())
+		}
+		fv.Set(id.Convert(fv.Type()))
+	}
+	return generated, nil
+}
+
+// UUID is a version 4 (random) UUID, the id of fields tagged pri:"uuid".
+// Such fields may be a UUID, a string or a [16]byte; all are written in the
+// canonical text form. For ids generated by the database instead, tag the
+// field pri:"key" with defaultExpr:"gen_random_uuid()" and leave it empty:
+// Insert reads it back with RETURNING.
+type UUID [16]byte
+
+// NewUUID returns a random UUID.
+func NewUUID() UUID {
+	var id UUID
+	rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	return id
+}
+
+// ParseUUID parses the canonical, hyphenated text form of a UUID.
+func ParseUUID(s string) (UUID, error) {
+	var id UUID
+	s = strings.ReplaceAll(s, "-", "")
+	if len(s) != 32 {
+		return id, fmt.Errorf("orm: invalid UUID %q", s)
+	}
+	if _, err := hex.Decode(id[:], []byte(s)); err != nil {
+		return id, fmt.Errorf("orm: invalid UUID %q", s)
+	}
+	return id, nil
+}
+
+func (u UUID) String() string {
+	var out [36]byte
+	hex.Encode(out[:], u[:4])
+	out[8] = '-'
+	hex.Encode(out[9:], u[4:6])
+	out[13] = '-'
+	hex.Encode(out[14:], u[6:8])
+	out[18] = '-'
+	hex.Encode(out[19:], u[8:10])
+	out[23] = '-'
+	hex.Encode(out[24:], u[10:])
+	return string(out[:])
+}
+
+// Value writes u as text; the zero UUID is NULL.
+func (u UUID) Value() (driver.Value, error) {
+	if u == (UUID{}) {
+		return nil, nil
+	}
+	return u.String(), nil
+}
+
+// Scan reads a UUID from its text form or 16 raw bytes.
+func (u *UUID) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*u = UUID{}
+		return nil
+	case []byte:
+		src = string(v)
+	}
+	if s, ok := src.(string); ok && len(s) == 16 {
+		copy(u[:], s)
+		return nil
+	}
+	s, ok := src.(string)
+	if !ok {
+		return fmt.Errorf("orm: cannot scan %T into UUID", src)
+	}
+	id, err := ParseUUID(s)
+	if err != nil {
+		return err
+	}
+	*u = id
+	return nil
+}
+
+func init() {
+	// Bare [16]byte fields are UUIDs too.
+	RegisterConverter(
+		func(b [16]byte) (any, error) { return UUID(b).Value() },
+		func(src any) ([16]byte, error) {
+			var id UUID
+			err := id.Scan(src)
+			return id, err
+		},
+	)
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var (
+	ulidMu   sync.Mutex
+	ulidLast [16]byte
+	ulidMs   uint64
+)
+
+// NewULID returns a monotonic ULID: ids generated within the same
+// millisecond increment the random part, so they always sort in creation
+// order within this process.
+func NewULID() string {
+	ms := uint64(time.Now().UnixMilli())
+	ulidMu.Lock()
+	var id [16]byte
+	if ms <= ulidMs {
+		id = ulidLast
+		for i := 15; i >= 6; i-- {
+			if id[i]++; id[i] != 0 {
+				break
+			}
+		}
+	} else {
+		ulidMs = ms
+		var ts [8]byte
+		binary.BigEndian.PutUint64(ts[:], ms)
+		copy(id[:6], ts[2:])
+		rand.Read(id[6:])
+	}
+	ulidLast = id
+	ulidMu.Unlock()
+	return encodeULID(id)
+}
+
+func encodeULID(id [16]byte) string {
+	var out [26]byte
+	// 128 bits in 26 base32 digits; the first digit carries 3 bits.
+	var bits uint
+	var acc uint32
+	pos := 25
+	for i := 15; i >= 0; i-- {
+		acc |= uint32(id[i]) << bits
+		bits += 8
+		for bits >= 5 && pos >= 0 {
+			out[pos] = crockford[acc&31]
+			acc >>= 5
+			bits -= 5
+			pos--
+		}
+	}
+	if pos >= 0 {
+		out[pos] = crockford[acc&31]
+	}
+	return string(out[:])
+}
+
+const (
+	base62      = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	ksuidEpoch  = 1400000000
+	ksuidLength = 27
+)
+
+var (
+	ksuidMu   sync.Mutex
+	ksuidLast [20]byte
+	ksuidSec  uint32
+)
+
+// NewKSUID returns a 27 character KSUID, monotonic within this process.
+func NewKSUID() string {
+	sec := uint32(time.Now().Unix() - ksuidEpoch)
+	ksuidMu.Lock()
+	var id [20]byte
+	if sec <= ksuidSec {
+		id = ksuidLast
+		for i := 19; i >= 4; i-- {
+			if id[i]++; id[i] != 0 {
+				break
+			}
+		}
+	} else {
+		ksuidSec = sec
+		binary.BigEndian.PutUint32(id[:4], sec)
+		rand.Read(id[4:])
+	}
+	ksuidLast = id
+	ksuidMu.Unlock()
+	return encodeBase62(id[:], ksuidLength)
+}
+
+func encodeBase62(src []byte, length int) string {
+	num := append([]byte(nil), src...)
+	out := make([]byte, length)
+	for pos := length - 1; pos >= 0; pos-- {
+		var rem uint32
+		for i := range num {
+			acc := rem<<8 | uint32(num[i])
+			num[i] = byte(acc / 62)
+			rem = acc % 62
+		}
+		out[pos] = base62[rem]
+	}
+	return string(out)
+}
+
+// SnowflakeNode identifies this process in snowflake ids (0-1023).
+var SnowflakeNode int64
+
+var snowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+
+var (
+	snowflakeMu  sync.Mutex
+	snowflakeMs  int64
+	snowflakeSeq int64
+)
+
+// NewSnowflake returns a 63 bit id made of a millisecond timestamp, the
+// SnowflakeNode and a per-millisecond sequence. It never goes backwards, even
+// if the wall clock does.
+func NewSnowflake() int64 {
+	snowflakeMu.Lock()
+	defer snowflakeMu.Unlock()
+	ms := time.Now().UnixMilli() - snowflakeEpoch
+	if ms < snowflakeMs {
+		ms = snowflakeMs
+	}
+	if ms == snowflakeMs {
+		snowflakeSeq = (snowflakeSeq + 1) & 0xFFF
+		if snowflakeSeq == 0 {
+			ms++
+		}
+	} else {
+		snowflakeSeq = 0
+	}
+	snowflakeMs = ms
+	return ms<<22 | (SnowflakeNode&0x3FF)<<12 | snowflakeSeq
+}
@@ -0,0 +1,46 @@
+package orm_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gobkc/orm"
+	"github.com/gobkc/orm/ormtest"
+)
+
+type dbUUIDRow struct {
+	ID   orm.UUID `pri:"key" defaultExpr:"gen_random_uuid()"`
+	Name string
+}
+
+func (dbUUIDRow) TableName() string { return "db_uuid_rows" }
+
+func TestCreateTableSQLPrimaryKeyDefault(t *testing.T) {
+	stmts := orm.CreateTableSQL[dbUUIDRow]()
+	if len(stmts) == 0 || !strings.Contains(stmts[0], "id uuid DEFAULT gen_random_uuid() PRIMARY KEY") {
+		t.Fatalf("primary key default missing: %q", stmts)
+	}
+}
+
+func TestInsertDatabaseGeneratedUUID(t *testing.T) {
+	db := ormtest.StartPostgres(t)
+	ctx := context.Background()
+	if err := orm.AutoMigrate[dbUUIDRow](ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := orm.Insert(ctx, db, []dbUUIDRow{{Name: "a"}, {Name: "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].ID == (orm.UUID{}) || rows[0].ID == rows[1].ID {
+		t.Fatalf("ids not read back: %v %v", rows[0].ID, rows[1].ID)
+	}
+	got, err := orm.First[dbUUIDRow](ctx, db, "id = $1", rows[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "a" {
+		t.Fatalf("got %+v", got)
+	}
+}
@@ -0,0 +1,83 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrLeaseHeld = errors.New("lease: held by another owner")
+	ErrLeaseLost = errors.New("lease: expired or taken over")
+)
+
+// Lease is the ownership of Key by Owner until ExpiresAt.
+type Lease struct {
+	Key       string
+	Owner     string
+	ExpiresAt time.Time
+}
+
+// Leases coordinates exclusive ownership of keys (partitions, shards) between
+// workers through a table. Expiry is evaluated with the database clock.
+type Leases struct {
+	DB    *sql.DB
+	Table string
+	Owner string
+}
+
+// NewLeases returns Leases for owner stored in the orm_leases table.
+func NewLeases(db *sql.DB, owner string) *Leases {
+	return &Leases{DB: db, Table: "orm_leases", Owner: owner}
+}
+
+// Init creates the lease table if it does not exist.
+func (l *Leases) Init(ctx context.Context) error {
+	_, err := l.DB.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	key text PRIMARY KEY,
+	owner text NOT NULL,
+	expires_at timestamptz NOT NULL
+)`, l.Table))
+	return err
+}
+
+// Acquire takes key for ttl if it is free, expired or already ours, and
+// returns ErrLeaseHeld otherwise.
+func (l *Leases) Acquire(ctx context.Context, key string, ttl time.Duration) (*Lease, error) {
+	sqlStr := fmt.Sprintf(`INSERT INTO %[1]s (key, owner, expires_at) VALUES ($1, $2, now() + make_interval(secs => $3::float8))
+ON CONFLICT (key) DO UPDATE SET owner = EXCLUDED.owner, expires_at = EXCLUDED.expires_at
+WHERE %[1]s.expires_at < now() OR %[1]s.owner = EXCLUDED.owner
+RETURNING key, owner, expires_at`, l.Table)
+	return l.scan(ctx, ErrLeaseHeld, sqlStr, key, l.Owner, ttl.Seconds())
+}
+
+// Renew extends a lease we still hold, or returns ErrLeaseLost.
+func (l *Leases) Renew(ctx context.Context, key string, ttl time.Duration) (*Lease, error) {
+	sqlStr := fmt.Sprintf(`UPDATE %s SET expires_at = now() + make_interval(secs => $3::float8)
+WHERE key = $1 AND owner = $2 AND expires_at >= now()
+RETURNING key, owner, expires_at`, l.Table)
+	return l.scan(ctx, ErrLeaseLost, sqlStr, key, l.Owner, ttl.Seconds())
+}
+
+// Release gives key up if we hold it.
+func (l *Leases) Release(ctx context.Context, key string) error {
+	sqlStr := fmt.Sprintf("DELETE FROM %s WHERE key = $1 AND owner = $2", l.Table)
+	outputSql(sqlStr, []any{key, l.Owner})
+	_, err := l.DB.ExecContext(ctx, sqlStr, key, l.Owner)
+	return err
+}
+
+func (l *Leases) scan(ctx context.Context, missing error, sqlStr string, args ...any) (*Lease, error) {
+	outputSql(sqlStr, args)
+	lease := new(Lease)
+	err := l.DB.QueryRowContext(ctx, sqlStr, args...).Scan(&lease.Key, &lease.Owner, &lease.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, missing
+	}
+	if err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
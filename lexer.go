@@ -0,0 +1,153 @@
+package orm
+
+import (
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokSpace tokenKind = iota
+	tokComment
+	tokWord
+	tokQuotedIdent
+	tokString
+	tokDollarString
+	tokNumber
+	tokParam
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+// lexSQL splits a Postgres statement into tokens. It understands quoted
+// identifiers, standard, E” and dollar-quoted strings, comments, numbers and
+// $N parameters; everything else is a single punctuation rune.
+func lexSQL(s string) []token {
+	var tokens []token
+	line := 1
+	for i := 0; i < len(s); {
+		start := i
+		kind := tokPunct
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			kind = tokSpace
+			for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r') {
+				i++
+			}
+		case c == '-' && i+1 < len(s) && s[i+1] == '-':
+			kind = tokComment
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			kind = tokComment
+			if end := strings.Index(s[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(s)
+			}
+		case c == '\'' || ((c == 'E' || c == 'e') && i+1 < len(s) && s[i+1] == '\''):
+			kind = tokString
+			if c != '\'' {
+				i++
+			}
+			i = scanQuoted(s, i, '\'', c != '\'')
+		case c == '"':
+			kind = tokQuotedIdent
+			i = scanQuoted(s, i, '"', false)
+		case c == '$' && i+1 < len(s) && isDigit(s[i+1]):
+			kind = tokParam
+			i++
+			for i < len(s) && isDigit(s[i]) {
+				i++
+			}
+		case c == '$':
+			if tag, ok := dollarTag(s[i:]); ok {
+				kind = tokDollarString
+				if end := strings.Index(s[i+len(tag):], tag); end >= 0 {
+					i += len(tag) + end + len(tag)
+				} else {
+					i = len(s)
+				}
+			} else {
+				i++
+			}
+		case isDigit(c) || (c == '.' && i+1 < len(s) && isDigit(s[i+1])):
+			kind = tokNumber
+			for i < len(s) && (isDigit(s[i]) || s[i] == '.' || s[i] == 'e' || s[i] == 'E') {
+				i++
+			}
+		case isWordStart(c):
+			kind = tokWord
+			for i < len(s) && (isWordStart(s[i]) || isDigit(s[i]) || s[i] == '$') {
+				i++
+			}
+		default:
+			i++
+		}
+		tokens = append(tokens, token{kind: kind, text: s[start:i], line: line})
+		line += strings.Count(s[start:i], "\n")
+	}
+	return tokens
+}
+
+func scanQuoted(s string, i int, quote byte, backslash bool) int {
+	for i++; i < len(s); i++ {
+		switch {
+		case backslash && s[i] == '\\':
+			i++
+		case s[i] == quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// dollarTag returns the opening tag ($$ or $name$) at the start of s.
+func dollarTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '$' {
+			return s[:i+1], true
+		}
+		if !isWordStart(s[i]) && !isDigit(s[i]) {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+var sqlKeywords = map[string]bool{}
+
+func init() {
+	for _, kw := range strings.Fields(`ADD ALL ALTER AND ANY AS ASC BEGIN BETWEEN BY CASCADE CASE CAST
+		COLUMN COMMIT CONFLICT CONSTRAINT CREATE CROSS CURRENT_DATE CURRENT_TIMESTAMP CURSOR DECLARE
+		DEFAULT DELETE DESC DISTINCT DO DROP ELSE END EXCEPT EXISTS FALSE FETCH FOR FOREIGN FROM FULL
+		GROUP HAVING ILIKE IN INDEX INNER INSERT INTERSECT INTO IS JOIN KEY LATERAL LEFT LIKE LIMIT
+		LOCAL MATCHED MERGE NOT NOTHING NULL OFFSET ON OR ORDER OUTER PRIMARY REFERENCES RETURNING
+		RIGHT ROLLBACK ROW SAVEPOINT SELECT SET SKIP TABLE TABLESAMPLE THEN TO TRUE TRUNCATE UNION
+		UNIQUE UPDATE USING VALUES WHEN WHERE WITH`) {
+		sqlKeywords[kw] = true
+	}
+}
+
+func isKeyword(word string) bool {
+	return sqlKeywords[strings.ToUpper(word)]
+}
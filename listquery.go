@@ -0,0 +1,174 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var MaxPageSize = 100
+
+// ListQuery holds validated list options for a model: the column projection,
+// filter condition, ordering and page window.
+type ListQuery struct {
+	Columns []string
+	Where   string
+	Args    []any
+	OrderBy []string
+	Limit   int
+	Offset  int
+}
+
+// ParseListQuery converts JSON:API style parameters (page[size], page[number],
+// page[limit], page[offset], sort=-created_at,name, fields=a,b or
+// fields[type]=a,b, filter[field]=op:value) into a ListQuery for T. The OData
+// aliases $top, $skip, $orderby ("name desc") and $select are accepted too.
+// Every field is checked against T.
+func ParseListQuery[T any](db *sql.DB, values url.Values) (*ListQuery, error) {
+	m := modelOf(lookupHandle(db), reflect.TypeOf(new(T)).Elem())
+	q := &ListQuery{Limit: MaxPageSize}
+	filters := url.Values{}
+	var (
+		size, number int
+		err          error
+	)
+	for key, list := range values {
+		if len(list) == 0 {
+			continue
+		}
+		value := list[0]
+		switch {
+		case key == "page[size]" || key == "page[limit]" || key == "$top":
+			if size, err = listInt(key, value); err != nil {
+				return nil, err
+			}
+		case key == "page[number]":
+			if number, err = listInt(key, value); err != nil {
+				return nil, err
+			}
+		case key == "page[offset]" || key == "$skip":
+			if q.Offset, err = listInt(key, value); err != nil {
+				return nil, err
+			}
+		case key == "sort" || key == "$orderby":
+			if q.OrderBy, err = listSort(m, value); err != nil {
+				return nil, err
+			}
+		case key == "fields" || key == "$select" || strings.HasPrefix(key, "fields["):
+			if q.Columns, err = listFields(m, value); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(key, "filter[") && strings.HasSuffix(key, "]"):
+			filters[key[len("filter["):len(key)-1]] = list
+		}
+	}
+	if size > 0 {
+		if size > MaxPageSize {
+			return nil, fmt.Errorf("%w: page size %d exceeds %d", ErrFilter, size, MaxPageSize)
+		}
+		q.Limit = size
+	}
+	if number > 1 {
+		q.Offset = (number - 1) * q.Limit
+	}
+	if q.Where, q.Args, err = FilterValues[T](db, filters); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// SQL renders the query against table.
+func (q *ListQuery) SQL(table string) string {
+	columns := "*"
+	if len(q.Columns) > 0 {
+		columns = strings.Join(q.Columns, ",")
+	}
+	sqlStr := fmt.Sprintf("SELECT %s FROM %s", columns, table)
+	if q.Where != "" {
+		sqlStr += " WHERE " + q.Where
+	}
+	if len(q.OrderBy) > 0 {
+		sqlStr += " ORDER BY " + strings.Join(q.OrderBy, ",")
+	}
+	if q.Limit > 0 {
+		sqlStr += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+	if q.Offset > 0 {
+		sqlStr += fmt.Sprintf(" OFFSET %d", q.Offset)
+	}
+	return sqlStr
+}
+
+// List runs q against the table of T, leaving out soft deleted rows unless
+// options hold Unscoped.
+func List[T any](ctx context.Context, db Executor, q *ListQuery, options ...QueryOption) ([]T, error) {
+	args := append([]any(nil), q.Args...)
+	for _, opt := range options {
+		args = append(args, opt)
+	}
+	opts := applyOptions(options)
+	scoped := *q
+	h := handleFor(route[T](db))
+	scoped.Where = scopedWhere[T](h, q.Where, opts)
+	rows, err := Query[[]T](ctx, db, scoped.SQL(opts.tableName(h, new(T))), args...)
+	if err != nil {
+		return nil, err
+	}
+	return *rows, nil
+}
+
+func listInt(key, value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: %s must be a non-negative integer", ErrFilter, key)
+	}
+	return n, nil
+}
+
+func listSort(m *model, value string) ([]string, error) {
+	var orderBy []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		dir := "ASC"
+		if strings.HasPrefix(item, "-") {
+			dir, item = "DESC", item[1:]
+		} else if parts := strings.Fields(item); len(parts) == 2 {
+			item = parts[0]
+			switch strings.ToUpper(parts[1]) {
+			case "ASC":
+			case "DESC":
+				dir = "DESC"
+			default:
+				return nil, fmt.Errorf("%w: invalid sort direction %q", ErrFilter, parts[1])
+			}
+		}
+		f, ok := m.selectable(item)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown sort field %q", ErrFilter, item)
+		}
+		orderBy = append(orderBy, f.Column+" "+dir)
+	}
+	return orderBy, nil
+}
+
+func listFields(m *model, value string) ([]string, error) {
+	var columns []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		f, ok := m.selectable(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrFilter, name)
+		}
+		columns = append(columns, f.Column)
+	}
+	return columns, nil
+}
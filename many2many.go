@@ -0,0 +1,165 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// joinSpec is a resolved many2many relation.
+type joinSpec struct {
+	table      string
+	ownerCol   string
+	relatedCol string
+	ownerKey   *field
+	relatedKey *field
+	related    reflect.Type
+}
+
+// joinOf resolves the many2many field sf of owner.
+func joinOf(h *handle, owner reflect.Type, sf reflect.StructField, rel relation) (*joinSpec, error) {
+	if rel.join == "" {
+		return nil, fmt.Errorf("%w: many2many %s.%s names no join table", ErrFilter, owner.Name(), sf.Name)
+	}
+	related := relatedType(sf)
+	ownerModel, relatedModel := modelOf(h, owner), modelOf(h, related)
+	j := &joinSpec{table: rel.join, ownerCol: rel.fk, relatedCol: rel.relatedFk, related: related}
+	if j.ownerCol == "" {
+		j.ownerCol = toSnake(owner.Name()) + "_id"
+	}
+	if j.relatedCol == "" {
+		j.relatedCol = toSnake(related.Name()) + "_id"
+	}
+	var ok bool
+	if j.ownerKey, ok = ownerModel.field(primaryKeyColumn(ownerModel)); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoPrimaryKey, owner.Name())
+	}
+	if j.relatedKey, ok = relatedModel.field(primaryKeyColumn(relatedModel)); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoPrimaryKey, related.Name())
+	}
+	return j, nil
+}
+
+// loadMany2Many loads a many2many relation of parents: the links first,
+// then the related rows they point at.
+func loadMany2Many(ctx context.Context, ex Executor, h *handle, parents []reflect.Value, sf reflect.StructField, rel relation, opts *queryOptions) (*relatedRows, error) {
+	j, err := joinOf(h, parents[0].Type(), sf, rel)
+	if err != nil {
+		return nil, err
+	}
+	keys := relationKeys(parents, j.ownerKey)
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	sqlStr := fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s IN (%s)",
+		j.ownerCol, j.relatedCol, j.table, j.ownerCol, placeholders(1, len(keys)))
+	start := time.Now()
+	query, args := rebind(h.sqlDialect(), sqlStr, keys)
+	rows, err := ex.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, wrapQueryError(err, sqlStr, j.table)
+	}
+	type link struct{ owner, related string }
+	var links []link
+	var relatedKeys []any
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var owner, related any
+		if err = rows.Scan(&owner, &related); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		l := link{}
+		l.owner, _ = relationKey(reflect.ValueOf(&owner))
+		l.related, _ = relationKey(reflect.ValueOf(&related))
+		links = append(links, l)
+		if !seen[l.related] {
+			seen[l.related] = true
+			relatedKeys = append(relatedKeys, related)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	outputTimed(sqlStr, keys, start)
+
+	loaded := &relatedRows{parentKey: j.ownerKey, rows: reflect.MakeSlice(reflect.SliceOf(j.related), 0, 0), byKey: make(map[string][]int)}
+	if len(relatedKeys) == 0 {
+		return loaded, nil
+	}
+	if loaded.rows, err = queryRelated(ctx, ex, h, j.related, "", j.relatedKey.Column, relatedKeys, opts); err != nil {
+		return nil, err
+	}
+	index := make(map[string]int, loaded.rows.Len())
+	for i := 0; i < loaded.rows.Len(); i++ {
+		if key, ok := relationKey(loaded.rows.Index(i).FieldByIndex(j.relatedKey.Index)); ok {
+			index[key] = i
+		}
+	}
+	for _, l := range links {
+		if i, ok := index[l.related]; ok {
+			loaded.byKey[l.owner] = append(loaded.byKey[l.owner], i)
+		}
+	}
+	return loaded, nil
+}
+
+// AppendAssociation links owner to related, rows or pointers to rows of
+// the model held by owner's many2many field, by inserting the missing rows
+// of the join table.
+//
+//	err := orm.AppendAssociation(ctx, db, user, "Roles", admin, editor)
+func AppendAssociation[T any](ctx context.Context, db Executor, owner T, field string, related ...any) error {
+	return associate(ctx, route[T](db), owner, field, related, func(j *joinSpec, d Dialect) string {
+		from := ""
+		if d.Name() == "mysql" {
+			from = " FROM DUAL"
+		}
+		return fmt.Sprintf("INSERT INTO %[1]s (%[2]s, %[3]s) SELECT $1, $2%[4]s WHERE NOT EXISTS (SELECT 1 FROM %[1]s WHERE %[2]s = $1 AND %[3]s = $2)",
+			j.table, j.ownerCol, j.relatedCol, from)
+	})
+}
+
+// RemoveAssociation unlinks owner from related by deleting their rows of
+// the join table of owner's many2many field. The related rows are kept.
+func RemoveAssociation[T any](ctx context.Context, db Executor, owner T, field string, related ...any) error {
+	return associate(ctx, route[T](db), owner, field, related, func(j *joinSpec, _ Dialect) string {
+		return fmt.Sprintf("DELETE FROM %s WHERE %s = $1 AND %s = $2", j.table, j.ownerCol, j.relatedCol)
+	})
+}
+
+// associate runs the statement built by stmt, taking the owner and related
+// keys as $1 and $2, once per related row in one transaction.
+func associate(ctx context.Context, db Executor, owner any, name string, related []any, stmt func(*joinSpec, Dialect) string) error {
+	ov := reflect.Indirect(reflect.ValueOf(owner))
+	h := handleFor(db)
+	sf, ok := ov.Type().FieldByName(name)
+	rel, isRel := relationOf(sf)
+	if !ok || !isRel || rel.kind != "many2many" {
+		return fmt.Errorf("%w: %s has no many2many relation %q", ErrFilter, ov.Type().Name(), name)
+	}
+	j, err := joinOf(h, ov.Type(), sf, rel)
+	if err != nil {
+		return err
+	}
+	ownerKey := reflect.Indirect(ov.FieldByIndex(j.ownerKey.Index)).Interface()
+	sqlStr := stmt(j, h.sqlDialect())
+	return Transaction(ctx, db, func(tx Executor) error {
+		for _, r := range related {
+			rv := reflect.Indirect(reflect.ValueOf(r))
+			if rv.Type() != j.related {
+				return fmt.Errorf("%w: %s.%s holds %s, not %s", ErrFilter, ov.Type().Name(), name, j.related.Name(), rv.Type().Name())
+			}
+			args := []any{ownerKey, reflect.Indirect(rv.FieldByIndex(j.relatedKey.Index)).Interface()}
+			start := time.Now()
+			query, queryArgs := rebind(h.sqlDialect(), sqlStr, args)
+			if _, err := tx.ExecContext(ctx, query, queryArgs...); err != nil {
+				return wrapQueryError(err, sqlStr, j.table)
+			}
+			outputTimed(sqlStr, args, start)
+		}
+		return nil
+	})
+}
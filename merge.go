@@ -0,0 +1,305 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var ErrMergeKeys = errors.New("merge: On must name at least one column")
+
+// MergeOptions describes how incoming rows are reconciled with the table.
+type MergeOptions struct {
+	// On lists the columns matching incoming rows to existing ones. With the
+	// ON CONFLICT fallback they must be covered by a unique index.
+	On []string
+	// Update lists the columns overwritten on a match; empty means every
+	// written column except On and the primary key.
+	Update []string
+	// DoNothing keeps matched rows untouched (insert missing rows only).
+	DoNothing bool
+	// DeleteWhen deletes matched rows satisfying this condition instead of
+	// updating them. It may refer to the table as t and the incoming row as
+	// s, and needs MERGE support.
+	DeleteWhen string
+}
+
+// Merge upserts rows into T's table. On Postgres 15+ it runs a single MERGE
+// statement; older servers get INSERT ... ON CONFLICT, which cannot express
+// DeleteWhen. Rows travel as one JSON parameter expanded with
+// json_populate_recordset, so every column keeps the table's own type.
+func Merge[T any](ctx context.Context, db Executor, rows []T, opts MergeOptions, options ...QueryOption) (int64, error) {
+	if len(opts.On) == 0 {
+		return 0, ErrMergeKeys
+	}
+	return mergeRows(ctx, db, rows, opts, options, func(plan *mergePlan, table string) (string, error) {
+		server, err := serverVersion(ctx, db)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case server.Supports(FeatureMerge):
+			return plan.mergeSql(table), nil
+		case opts.DeleteWhen != "":
+			return "", server.Require(FeatureMerge)
+		}
+		if err = server.Require(FeatureOnConflict); err != nil {
+			return "", err
+		}
+		return plan.upsertSql(table), nil
+	})
+}
+
+// mergeRows plans and runs a Merge or Upsert; render picks the statement.
+func mergeRows[T any](ctx context.Context, db Executor, rows []T, opts MergeOptions, options []QueryOption, render func(plan *mergePlan, table string) (string, error)) (int64, error) {
+	db = route[T](db)
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	h := handleFor(db)
+	table := applyOptions(options).tableName(h, new(T))
+	plan, err := newMergePlan(modelOf(h, reflect.TypeOf(new(T)).Elem()), opts)
+	if err != nil {
+		return 0, err
+	}
+	sqlStr, err := render(plan, table)
+	if err != nil {
+		return 0, err
+	}
+	payload, err := mergePayload(h, plan, rows)
+	if err != nil {
+		return 0, err
+	}
+	defer outputTimed(sqlStr, []any{payload}, time.Now())
+	var result sql.Result
+	if mirror := mirrorOf(h, table); mirror != nil {
+		result, err = mirror.exec(ctx, db, sqlStr, payload)
+	} else {
+		result, err = db.ExecContext(ctx, sqlStr, payload)
+	}
+	if err != nil {
+		return 0, wrapQueryError(err, sqlStr, table)
+	}
+	return result.RowsAffected()
+}
+
+type mergePlan struct {
+	opts    MergeOptions
+	insert  []string
+	update  []string
+	keyCols []string
+	// sparse maps the columns Insert writes no value for when zero to
+	// their default. Their zeros travel as NULL and become the default on
+	// insert and keep the current value on update.
+	sparse map[string]string
+}
+
+// sparseField reports whether a zero f is left out of the row like Insert
+// leaves it out, and the default then written.
+func sparseField(f *field) (string, bool) {
+	if _, ok := zeroDefault(f.Tag, f.Type); !ok && !hasOrmOption(f.Tag, "omitempty") {
+		return "", false
+	}
+	return columnDefault(f), true
+}
+
+// value is the incoming value of column c in the row alias s.
+func (p *mergePlan) value(c string) string {
+	if d, ok := p.sparse[c]; ok && d != "" {
+		return fmt.Sprintf("COALESCE(s.%s, %s)", c, d)
+	}
+	return "s." + c
+}
+
+// set is the assignment of column c on a match, from the incoming row in.
+func (p *mergePlan) set(c, in string) string {
+	if _, ok := p.sparse[c]; ok {
+		return fmt.Sprintf("%s = COALESCE(%s.%s, t.%s)", c, in, c, c)
+	}
+	return fmt.Sprintf("%s = %s.%s", c, in, c)
+}
+
+func newMergePlan(m *model, opts MergeOptions) (*mergePlan, error) {
+	p := &mergePlan{opts: opts, sparse: make(map[string]string)}
+	keys := make(map[string]bool)
+	for _, name := range opts.On {
+		f, ok := m.selectable(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrFilter, name)
+		}
+		keys[f.Column] = true
+		p.keyCols = append(p.keyCols, f.Column)
+	}
+	for _, f := range m.Fields {
+		if f.Generated != "" {
+			continue
+		}
+		if _, generated := idGenerator(f.Tag); f.Primary && !generated && !keys[f.Column] {
+			continue
+		}
+		p.insert = append(p.insert, f.Column)
+		if d, ok := sparseField(f); ok && !keys[f.Column] {
+			p.sparse[f.Column] = d
+		}
+		if !keys[f.Column] && !f.Primary && len(opts.Update) == 0 && f.Tag.Get("upsert") != "-" {
+			p.update = append(p.update, f.Column)
+		}
+	}
+	for _, name := range opts.Update {
+		f, ok := m.selectable(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrFilter, name)
+		}
+		p.update = append(p.update, f.Column)
+	}
+	return p, nil
+}
+
+func (p *mergePlan) mergeSql(table string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "MERGE INTO %s AS t USING json_populate_recordset(NULL::%s, $1) AS s ON ", table, table)
+	conds := make([]string, len(p.keyCols))
+	for i, c := range p.keyCols {
+		conds[i] = fmt.Sprintf("t.%s = s.%s", c, c)
+	}
+	b.WriteString(strings.Join(conds, " AND "))
+	if p.opts.DeleteWhen != "" {
+		fmt.Fprintf(&b, " WHEN MATCHED AND (%s) THEN DELETE", p.opts.DeleteWhen)
+	}
+	if !p.opts.DoNothing && len(p.update) > 0 {
+		sets := make([]string, len(p.update))
+		for i, c := range p.update {
+			sets[i] = p.set(c, "s")
+		}
+		fmt.Fprintf(&b, " WHEN MATCHED THEN UPDATE SET %s", strings.Join(sets, ", "))
+	}
+	values := make([]string, len(p.insert))
+	for i, c := range p.insert {
+		values[i] = p.value(c)
+	}
+	fmt.Fprintf(&b, " WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)", strings.Join(p.insert, ", "), strings.Join(values, ", "))
+	return b.String()
+}
+
+func (p *mergePlan) upsertSql(table string) string {
+	values := make([]string, len(p.insert))
+	for i, c := range p.insert {
+		values[i] = p.value(c)
+	}
+	sqlStr := fmt.Sprintf("INSERT INTO %s AS t (%s) SELECT %s FROM json_populate_recordset(NULL::%s, $1) AS s ON CONFLICT (%s) ",
+		table, strings.Join(p.insert, ", "), strings.Join(values, ", "), table, strings.Join(p.keyCols, ", "))
+	if p.opts.DoNothing || len(p.update) == 0 {
+		return sqlStr + "DO NOTHING"
+	}
+	sets := make([]string, len(p.update))
+	for i, c := range p.update {
+		sets[i] = p.set(c, "EXCLUDED")
+	}
+	return sqlStr + "DO UPDATE SET " + strings.Join(sets, ", ")
+}
+
+// mergePayload encodes rows as a JSON array of objects keyed by column,
+// filling client generated keys first.
+func mergePayload[T any](h *handle, plan *mergePlan, rows []T) (string, error) {
+	list := make([]map[string]any, len(rows))
+	for i := range rows {
+		if _, err := assignIDs(h, &rows[i]); err != nil {
+			return "", err
+		}
+		obj, err := rowObject(h, plan, reflect.ValueOf(rows[i]))
+		if err != nil {
+			return "", err
+		}
+		list[i] = obj
+	}
+	data, err := json.Marshal(list)
+	return string(data), err
+}
+
+// rowObject maps the written columns of v to JSON friendly values; zero
+// sparse columns of plan are left NULL.
+func rowObject(h *handle, plan *mergePlan, v reflect.Value) (map[string]any, error) {
+	obj := make(map[string]any)
+	for _, f := range modelOf(h, v.Type()).Fields {
+		if f.Generated != "" {
+			continue
+		}
+		fv := v.FieldByIndex(f.Index)
+		if zeroIntent(f.Tag, fv) == zeroNull {
+			obj[f.Column] = nil
+			continue
+		}
+		if _, sparse := plan.sparse[f.Column]; sparse && fv.IsZero() {
+			obj[f.Column] = nil
+			continue
+		}
+		switch {
+		case f.Serializer != "":
+			if fv.Kind() == reflect.Pointer && fv.IsNil() {
+				obj[f.Column] = nil
+				continue
+			}
+			ser, ok := serializerOf(f.Serializer)
+			if !ok {
+				return nil, fmt.Errorf("orm: unknown serializer %q", f.Serializer)
+			}
+			data, err := ser.Marshal(fv.Interface())
+			if err != nil {
+				return nil, err
+			}
+			if json.Valid(data) {
+				obj[f.Column] = json.RawMessage(data)
+			} else {
+				obj[f.Column] = string(data)
+			}
+		case f.Composite != "":
+			if text, ok := compositeText(fv); ok {
+				obj[f.Column] = text
+			} else {
+				obj[f.Column] = nil
+			}
+		default:
+			if conv, ok := convertedField(fv.Type()); ok {
+				if fv.Kind() == reflect.Pointer && fv.IsNil() {
+					obj[f.Column] = nil
+					continue
+				}
+				if _, direct := converterOf(fv.Type()); !direct {
+					fv = fv.Elem()
+				}
+				val, err := conv.encode(fv)
+				if err != nil {
+					return nil, err
+				}
+				if b, ok := val.([]byte); ok {
+					val = string(b)
+				}
+				obj[f.Column] = val
+				continue
+			}
+			val := fv.Interface()
+			if valuer, ok := val.(driver.Valuer); ok && fv.Type() != reflect.TypeOf(time.Time{}) {
+				if fv.Kind() == reflect.Pointer && fv.IsNil() {
+					obj[f.Column] = nil
+					continue
+				}
+				dv, err := valuer.Value()
+				if err != nil {
+					return nil, err
+				}
+				if b, ok := dv.([]byte); ok {
+					dv = string(b)
+				}
+				val = dv
+			}
+			obj[f.Column] = val
+		}
+	}
+	return obj, nil
+}
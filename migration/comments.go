@@ -0,0 +1,14 @@
+package migration
+
+import "github.com/gobkc/orm"
+
+// Comments returns a migration applying the comment tags of T as COMMENT ON
+// TABLE/COLUMN statements. Going down keeps the comments.
+func Comments[T any](version int64) Migration {
+	return Migration{
+		Version: version,
+		Name:    "comments on " + orm.TableName[T](),
+		Up:      SQL(orm.CommentStatements[T]()...),
+		Down:    SQL(),
+	}
+}
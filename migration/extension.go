@@ -0,0 +1,67 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrExtensionUnavailable = errors.New("extension is not installed on the server")
+	ErrExtensionPrivilege   = errors.New("insufficient privilege to create extension")
+)
+
+// RequireExtension returns a Step ensuring each extension (e.g. "uuid-ossp",
+// "pg_trgm", "postgis") exists. Installed extensions are left alone, so the
+// step needs no special privileges once a DBA has created them.
+func RequireExtension(names ...string) Step {
+	return StepFunc(func(ctx context.Context, conn Conn) error {
+		for _, name := range names {
+			if err := requireExtension(ctx, conn, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func requireExtension(ctx context.Context, conn Conn, name string) error {
+	var installed, available bool
+	err := conn.QueryRowContext(ctx, `SELECT
+	EXISTS (SELECT 1 FROM pg_extension WHERE extname = $1),
+	EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = $1)`, name).Scan(&installed, &available)
+	if err != nil {
+		return err
+	}
+	if installed {
+		return nil
+	}
+	if !available {
+		return fmt.Errorf("%w: %s", ErrExtensionUnavailable, name)
+	}
+	// A failed CREATE would abort the migration's tx; the savepoint keeps
+	// the error readable without poisoning later statements.
+	_, inTx := conn.(*sql.Tx)
+	if inTx {
+		if _, err = conn.ExecContext(ctx, "SAVEPOINT orm_extension"); err != nil {
+			return err
+		}
+	}
+	_, err = conn.ExecContext(ctx, `CREATE EXTENSION IF NOT EXISTS "`+strings.ReplaceAll(name, `"`, `""`)+`"`)
+	if err != nil {
+		if inTx {
+			conn.ExecContext(ctx, "ROLLBACK TO SAVEPOINT orm_extension")
+		}
+		var state interface{ SQLState() string }
+		if errors.As(err, &state) && state.SQLState() == "42501" {
+			return fmt.Errorf("%w %s (ask a superuser to run CREATE EXTENSION %q): %v", ErrExtensionPrivilege, name, name, err)
+		}
+		return err
+	}
+	if inTx {
+		_, err = conn.ExecContext(ctx, "RELEASE SAVEPOINT orm_extension")
+	}
+	return err
+}
@@ -0,0 +1,156 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gobkc/orm"
+)
+
+var ErrUnsafe = errors.New("migration has locking hazards")
+
+// Hazard is a statement likely to hold a heavy lock on a large table.
+type Hazard struct {
+	Rule      string
+	Statement string
+	Message   string
+}
+
+func (h Hazard) String() string {
+	return fmt.Sprintf("%s: %s\n\t%s", h.Rule, h.Message, h.Statement)
+}
+
+// UnsafeError lists the hazards of a migration not marked Unsafe.
+type UnsafeError struct {
+	Version int64
+	Name    string
+	Hazards []Hazard
+}
+
+func (e *UnsafeError) Error() string {
+	list := make([]string, len(e.Hazards))
+	for i, h := range e.Hazards {
+		list[i] = h.String()
+	}
+	return fmt.Sprintf("migration %d %s: %v (set Unsafe to override):\n%s", e.Version, e.Name, ErrUnsafe, strings.Join(list, "\n"))
+}
+
+func (e *UnsafeError) Unwrap() error {
+	return ErrUnsafe
+}
+
+var (
+	lintCreateTable = regexp.MustCompile(`^CREATE (?:UNLOGGED )?TABLE (?:IF NOT EXISTS )?([^\s(]+)`)
+	lintCreateIndex = regexp.MustCompile(`^CREATE (?:UNIQUE )?INDEX (CONCURRENTLY )?(?:.*? )?ON (?:ONLY )?([^\s(]+)`)
+	lintAlterTable  = regexp.MustCompile(`^ALTER TABLE (?:IF EXISTS )?(?:ONLY )?([^\s]+)`)
+	lintAddDefault  = regexp.MustCompile(`ADD (?:COLUMN )?(?:IF NOT EXISTS )?\S+ [^,]*?(DEFAULT \S[^,]*|BIGSERIAL|SERIAL|GENERATED ALWAYS AS)`)
+	lintAlterType   = regexp.MustCompile(`ALTER (?:COLUMN )?\S+ (?:SET DATA )?TYPE `)
+	lintSetNotNull  = regexp.MustCompile(`ALTER (?:COLUMN )?\S+ SET NOT NULL`)
+	lintAddCheck    = regexp.MustCompile(`ADD (?:CONSTRAINT \S+ )?(?:FOREIGN KEY|CHECK)`)
+	lintVolatile    = regexp.MustCompile(`\b(?:RANDOM|CLOCK_TIMESTAMP|TIMEOFDAY|GEN_RANDOM_UUID|UUID_GENERATE_V\d\w*|NEXTVAL)\s*\(`)
+)
+
+// Lint reports statements that take an ACCESS EXCLUSIVE lock for a table
+// scan or rewrite, or block writes while building an index. versionNum is the
+// server_version_num the statements target; zero assumes an old server.
+// Statements against tables created earlier in the same list are ignored.
+func Lint(stmts []string, versionNum int) []Hazard {
+	var hazards []Hazard
+	created := make(map[string]bool)
+	for _, raw := range stmts {
+		for _, stmt := range splitStatements(raw) {
+			s := normalize(stmt)
+			add := func(rule, msg string) {
+				hazards = append(hazards, Hazard{Rule: rule, Statement: stmt, Message: msg})
+			}
+			if m := lintCreateTable.FindStringSubmatch(s); m != nil {
+				created[m[1]] = true
+				continue
+			}
+			if m := lintCreateIndex.FindStringSubmatch(s); m != nil {
+				if m[1] == "" && !created[m[2]] {
+					add("create-index", "CREATE INDEX blocks writes for the whole build; use CONCURRENTLY in a NoTx migration")
+				}
+				continue
+			}
+			m := lintAlterTable.FindStringSubmatch(s)
+			if m == nil || created[m[1]] {
+				continue
+			}
+			if d := lintAddDefault.FindStringSubmatch(s); d != nil {
+				switch {
+				case !strings.HasPrefix(d[1], "DEFAULT"):
+					add("add-column-default", "adding a serial or generated column rewrites the table")
+				case versionNum < 110000:
+					add("add-column-default", "ADD COLUMN with DEFAULT rewrites the table before Postgres 11")
+				case lintVolatile.MatchString(d[1]):
+					add("add-column-default", "ADD COLUMN with a volatile DEFAULT rewrites the table")
+				}
+			}
+			if lintAlterType.MatchString(s) {
+				add("alter-type", "changing a column type usually rewrites the table and its indexes")
+			}
+			if lintSetNotNull.MatchString(s) {
+				add("set-not-null", "SET NOT NULL scans the table under an exclusive lock; add a CHECK (... IS NOT NULL) NOT VALID constraint and validate it first")
+			}
+			if lintAddCheck.MatchString(s) && !strings.Contains(s, "NOT VALID") {
+				add("add-constraint", "adding a FOREIGN KEY or CHECK validates every row under lock; add it NOT VALID and VALIDATE CONSTRAINT later")
+			}
+		}
+	}
+	return hazards
+}
+
+// normalize upper-cases s outside string literals and collapses whitespace.
+func normalize(s string) string {
+	var b strings.Builder
+	inString := false
+	for _, r := range s {
+		if r == '\'' {
+			inString = !inString
+		}
+		if !inString {
+			r = []rune(strings.ToUpper(string(r)))[0]
+		}
+		b.WriteRune(r)
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// splitStatements splits a script into statements with orm.SplitScript.
+func splitStatements(s string) []string {
+	var out []string
+	for _, stmt := range orm.SplitScript(s) {
+		out = append(out, stmt.SQL)
+	}
+	return out
+}
+
+// LintMigration lints the statically known SQL of mig's Up step.
+func LintMigration(mig Migration, versionNum int) []Hazard {
+	return Lint(statements(mig.Up), versionNum)
+}
+
+// check lints every pending migration not marked Unsafe.
+func (m *Migrator) check(ctx context.Context, applied map[int64]bool) error {
+	var versionNum int
+	for _, mig := range m.migrations {
+		if applied[mig.Version] || mig.Unsafe {
+			continue
+		}
+		if versionNum == 0 {
+			server, err := orm.ServerInfo(ctx, m.DB)
+			if err != nil {
+				return err
+			}
+			versionNum = server.VersionNum
+		}
+		if hazards := LintMigration(mig, versionNum); len(hazards) > 0 {
+			return &UnsafeError{Version: mig.Version, Name: mig.Name, Hazards: hazards}
+		}
+	}
+	return nil
+}
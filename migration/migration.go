@@ -0,0 +1,273 @@
+// Package migration applies versioned schema changes to a Postgres database.
+// Each migration runs in its own transaction and is recorded in a version
+// table; concurrent migrators are serialised with an advisory lock.
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/gobkc/orm"
+)
+
+// DefaultTable records applied versions.
+const DefaultTable = "schema_migrations"
+
+// lockKey serialises migrators across processes.
+const lockKey = 0x6f726d6d6967
+
+// Conn is what a step runs against: the migration's *sql.Tx, or a *sql.Conn
+// for NoTx migrations.
+type Conn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Step is one direction of a migration.
+type Step interface {
+	Run(ctx context.Context, conn Conn) error
+}
+
+// StepFunc adapts a function to Step.
+type StepFunc func(ctx context.Context, conn Conn) error
+
+func (f StepFunc) Run(ctx context.Context, conn Conn) error {
+	return f(ctx, conn)
+}
+
+// sqlStep runs statements in order; Lint can inspect them.
+type sqlStep []string
+
+// SQL returns a Step executing the statements in order.
+func SQL(stmts ...string) Step {
+	return sqlStep(stmts)
+}
+
+func (s sqlStep) Run(ctx context.Context, conn Conn) error {
+	for _, stmt := range s {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%w\n%s", err, stmt)
+		}
+	}
+	return nil
+}
+
+type chain []Step
+
+// Steps chains steps into one.
+func Steps(steps ...Step) Step {
+	return chain(steps)
+}
+
+func (c chain) Run(ctx context.Context, conn Conn) error {
+	for _, step := range c {
+		if step == nil {
+			continue
+		}
+		if err := step.Run(ctx, conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statements returns the SQL known statically for step.
+func statements(step Step) []string {
+	switch s := step.(type) {
+	case sqlStep:
+		return s
+	case chain:
+		var out []string
+		for _, sub := range s {
+			out = append(out, statements(sub)...)
+		}
+		return out
+	}
+	return nil
+}
+
+// Migration is one versioned change. Down may be nil for irreversible ones.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      Step
+	Down    Step
+	// NoTx runs the steps outside a transaction, as CREATE INDEX
+	// CONCURRENTLY requires. A failure may leave the change half applied.
+	NoTx bool
+	// Unsafe acknowledges the locking hazards reported by Lint.
+	Unsafe bool
+}
+
+// Migrator applies registered migrations.
+type Migrator struct {
+	DB *sql.DB
+	// Table records applied versions; when schema qualified the schema is
+	// created on first use.
+	Table string
+
+	migrations []Migration
+}
+
+// New returns a Migrator recording versions in DefaultTable.
+func New(db *sql.DB, migrations ...Migration) *Migrator {
+	m := &Migrator{DB: db, Table: DefaultTable}
+	m.Add(migrations...)
+	return m
+}
+
+// Add registers migrations; they are kept sorted by version.
+func (m *Migrator) Add(migrations ...Migration) {
+	m.migrations = append(m.migrations, migrations...)
+	sort.SliceStable(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+}
+
+func (m *Migrator) init(ctx context.Context) error {
+	if schema, _ := orm.SplitTable(m.Table); schema != "" {
+		if _, err := m.DB.ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+orm.QuoteTable(schema)); err != nil {
+			return err
+		}
+	}
+	_, err := m.DB.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version bigint PRIMARY KEY,
+	name text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`, m.Table))
+	return err
+}
+
+// Applied returns the recorded versions.
+func (m *Migrator) Applied(ctx context.Context) (map[int64]bool, error) {
+	if err := m.init(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := m.DB.QueryContext(ctx, "SELECT version FROM "+m.Table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var v int64
+		if err = rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+// Up applies every pending migration in version order and returns the
+// versions applied. Pending migrations are linted first; any hazard in a
+// migration not marked Unsafe stops the run before anything is applied.
+func (m *Migrator) Up(ctx context.Context) ([]int64, error) {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.check(ctx, applied); err != nil {
+		return nil, err
+	}
+	var done []int64
+	for _, mig := range m.migrations {
+		if applied[mig.Version] {
+			continue
+		}
+		ran, err := m.run(ctx, mig, true)
+		if err != nil {
+			return done, fmt.Errorf("migration %d %s: %w", mig.Version, mig.Name, err)
+		}
+		if !ran {
+			continue
+		}
+		log.Printf("[ORM INFO]\t migrated up %d %s\n", mig.Version, mig.Name)
+		done = append(done, mig.Version)
+	}
+	return done, nil
+}
+
+// Down reverts the last n applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, n int) ([]int64, error) {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var done []int64
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < n; i-- {
+		mig := m.migrations[i]
+		if !applied[mig.Version] {
+			continue
+		}
+		if mig.Down == nil {
+			return done, fmt.Errorf("migration %d %s is irreversible", mig.Version, mig.Name)
+		}
+		ran, err := m.run(ctx, mig, false)
+		if err != nil {
+			return done, fmt.Errorf("migration %d %s: %w", mig.Version, mig.Name, err)
+		}
+		if !ran {
+			continue
+		}
+		log.Printf("[ORM INFO]\t migrated down %d %s\n", mig.Version, mig.Name)
+		done = append(done, mig.Version)
+	}
+	return done, nil
+}
+
+// run applies one direction of mig under the advisory lock. It reports false
+// when another migrator got there first.
+func (m *Migrator) run(ctx context.Context, mig Migration, up bool) (bool, error) {
+	step, record := mig.Up, "INSERT INTO "+m.Table+" (version, name) VALUES ($1, $2)"
+	args := []any{mig.Version, mig.Name}
+	if !up {
+		step, record = mig.Down, "DELETE FROM "+m.Table+" WHERE version = $1"
+		args = args[:1]
+	}
+	apply := func(conn Conn) (bool, error) {
+		var exists bool
+		if err := conn.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM "+m.Table+" WHERE version = $1)", mig.Version).Scan(&exists); err != nil {
+			return false, err
+		}
+		if exists == up {
+			return false, nil
+		}
+		if step != nil {
+			if err := step.Run(ctx, conn); err != nil {
+				return false, err
+			}
+		}
+		_, err := conn.ExecContext(ctx, record, args...)
+		return err == nil, err
+	}
+	if mig.NoTx {
+		conn, err := m.DB.Conn(ctx)
+		if err != nil {
+			return false, err
+		}
+		defer conn.Close()
+		if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", int64(lockKey)); err != nil {
+			return false, err
+		}
+		defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", int64(lockKey))
+		return apply(conn)
+	}
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	if _, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", int64(lockKey)); err != nil {
+		return false, err
+	}
+	ran, err := apply(tx)
+	if err != nil || !ran {
+		return false, err
+	}
+	return true, tx.Commit()
+}
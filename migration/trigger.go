@@ -0,0 +1,184 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gobkc/orm"
+)
+
+// Function is a stored function, typically a trigger function.
+type Function struct {
+	Name string
+	// Args is the argument list, e.g. "a int, b text"; empty for triggers.
+	Args string
+	// Returns defaults to "trigger".
+	Returns string
+	// Language defaults to "plpgsql".
+	Language string
+	Body     string
+}
+
+// CreateSQL returns an idempotent CREATE OR REPLACE FUNCTION statement.
+func (f Function) CreateSQL() string {
+	returns, lang := f.Returns, f.Language
+	if returns == "" {
+		returns = "trigger"
+	}
+	if lang == "" {
+		lang = "plpgsql"
+	}
+	return fmt.Sprintf("CREATE OR REPLACE FUNCTION %s(%s) RETURNS %s LANGUAGE %s AS $orm$\n%s\n$orm$",
+		f.Name, f.Args, returns, lang, strings.TrimSpace(f.Body))
+}
+
+// DropSQL returns a DROP FUNCTION IF EXISTS statement.
+func (f Function) DropSQL() string {
+	return fmt.Sprintf("DROP FUNCTION IF EXISTS %s(%s)", f.Name, f.Args)
+}
+
+// Trigger attaches a function to a table.
+type Trigger struct {
+	Name  string
+	Table string
+	// Timing is BEFORE, AFTER or INSTEAD OF; defaults to BEFORE.
+	Timing string
+	// Events lists INSERT, UPDATE, DELETE or TRUNCATE; defaults to UPDATE.
+	Events []string
+	// Statement fires once per statement instead of per row.
+	Statement bool
+	// When is an optional condition, e.g. "OLD.* IS DISTINCT FROM NEW.*".
+	When     string
+	Function string
+	// Args are passed to the function as TG_ARGV literals.
+	Args []string
+}
+
+// CreateSQL drops any trigger of the same name and recreates it, which is
+// idempotent on every server version (CREATE OR REPLACE TRIGGER needs 14).
+func (t Trigger) CreateSQL() []string {
+	timing, events := t.Timing, t.Events
+	if timing == "" {
+		timing = "BEFORE"
+	}
+	if len(events) == 0 {
+		events = []string{"UPDATE"}
+	}
+	each := "ROW"
+	if t.Statement {
+		each = "STATEMENT"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TRIGGER %s %s %s ON %s FOR EACH %s", t.Name, timing, strings.Join(events, " OR "), t.Table, each)
+	if t.When != "" {
+		fmt.Fprintf(&b, " WHEN (%s)", t.When)
+	}
+	args := make([]string, len(t.Args))
+	for i, a := range t.Args {
+		args[i] = "'" + strings.ReplaceAll(a, "'", "''") + "'"
+	}
+	fmt.Fprintf(&b, " EXECUTE PROCEDURE %s(%s)", t.Function, strings.Join(args, ", "))
+	return []string{t.DropSQL(), b.String()}
+}
+
+// DropSQL returns a DROP TRIGGER IF EXISTS statement.
+func (t Trigger) DropSQL() string {
+	return fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", t.Name, t.Table)
+}
+
+// Triggers returns a migration creating fn (when it has a name) and the
+// triggers on the way up, and dropping them on the way down. The function
+// is dropped last, so shared functions should be created in their own
+// migration instead.
+func Triggers(version int64, name string, fn Function, triggers ...Trigger) Migration {
+	var up, down []string
+	if fn.Name != "" {
+		up = append(up, fn.CreateSQL())
+	}
+	for _, t := range triggers {
+		up = append(up, t.CreateSQL()...)
+		down = append(down, t.DropSQL())
+	}
+	if fn.Name != "" {
+		down = append(down, fn.DropSQL())
+	}
+	return Migration{Version: version, Name: name, Up: SQL(up...), Down: SQL(down...)}
+}
+
+// UpdatedAtFunction sets the column named by the first trigger argument to
+// now(); one function serves every table.
+var UpdatedAtFunction = Function{
+	Name: "orm_set_updated_at",
+	Body: `BEGIN
+	NEW := jsonb_populate_record(NEW, jsonb_build_object(TG_ARGV[0], now()));
+	RETURN NEW;
+END;`,
+}
+
+// UpdatedAt returns a BEFORE UPDATE trigger keeping column current on table.
+// Create UpdatedAtFunction first, e.g. with Triggers(v, name, UpdatedAtFunction).
+func UpdatedAt(table, column string) Trigger {
+	return Trigger{
+		Name:     triggerName(table, column),
+		Table:    table,
+		Timing:   "BEFORE",
+		Events:   []string{"UPDATE"},
+		Function: UpdatedAtFunction.Name,
+		Args:     []string{column},
+	}
+}
+
+// AuditTable is the table written by AuditFunction.
+const AuditTable = "orm_audit"
+
+// AuditFunction appends every row change to AuditTable as jsonb.
+var AuditFunction = Function{
+	Name: "orm_audit",
+	Body: `BEGIN
+	IF TG_OP = 'DELETE' THEN
+		INSERT INTO ` + AuditTable + ` (table_name, op, old_row) VALUES (TG_TABLE_NAME, TG_OP, to_jsonb(OLD));
+		RETURN OLD;
+	END IF;
+	INSERT INTO ` + AuditTable + ` (table_name, op, old_row, new_row)
+	VALUES (TG_TABLE_NAME, TG_OP, CASE WHEN TG_OP = 'UPDATE' THEN to_jsonb(OLD) END, to_jsonb(NEW));
+	RETURN NEW;
+END;`,
+}
+
+// AuditSetup returns a migration creating AuditTable and AuditFunction.
+// Down drops both, so revert the audited tables' triggers first.
+func AuditSetup(version int64) Migration {
+	return Migration{
+		Version: version,
+		Name:    "orm audit",
+		Up: SQL(`CREATE TABLE IF NOT EXISTS `+AuditTable+` (
+	id bigserial PRIMARY KEY,
+	table_name text NOT NULL,
+	op text NOT NULL,
+	old_row jsonb,
+	new_row jsonb,
+	changed_by text NOT NULL DEFAULT current_user,
+	changed_at timestamptz NOT NULL DEFAULT now()
+)`, AuditFunction.CreateSQL()),
+		Down: SQL(AuditFunction.DropSQL(), "DROP TABLE IF EXISTS "+AuditTable),
+	}
+}
+
+// Audit returns an AFTER INSERT OR UPDATE OR DELETE trigger recording changes
+// to table through AuditFunction.
+func Audit(table string) Trigger {
+	return Trigger{
+		Name:     triggerName(table, "audit"),
+		Table:    table,
+		Timing:   "AFTER",
+		Events:   []string{"INSERT", "UPDATE", "DELETE"},
+		Function: AuditFunction.Name,
+	}
+}
+
+// triggerName names a trigger on table after its unqualified name, since
+// trigger names belong to the table and cannot carry a schema.
+func triggerName(table, suffix string) string {
+	_, name := orm.SplitTable(table)
+	return orm.Postgres.QuoteIdent(name + "_" + suffix)
+}
@@ -0,0 +1,119 @@
+package orm
+
+import (
+	"database/sql"
+	"reflect"
+	"sync"
+)
+
+// field is the mapping of one struct field to a column.
+type field struct {
+	Name    string
+	Column  string
+	Index   []int
+	Type    reflect.Type
+	Tag     reflect.StructTag
+	Primary bool
+	// Composite names the Postgres composite type of a nested struct.
+	Composite string
+	// Serializer names the Serializer storing the field in one column.
+	Serializer string
+	// Generated is the expression of a GENERATED ALWAYS ... STORED column.
+	// Such columns are scanned but never written.
+	Generated string
+}
+
+// model is the cached column mapping of a struct type.
+type model struct {
+	Type     reflect.Type
+	Fields   []*field
+	byColumn map[string]*field
+}
+
+type modelKey struct {
+	t reflect.Type
+	h *handle
+}
+
+var models sync.Map
+
+func modelOf(h *handle, t reflect.Type) *model {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	key := modelKey{t: t, h: h}
+	if m, ok := models.Load(key); ok {
+		return m.(*model)
+	}
+	m := buildModel(h, t)
+	actual, _ := models.LoadOrStore(key, m)
+	return actual.(*model)
+}
+
+// buildModel maps the columns of t. Fields tagged pri make up the primary
+// key, several of them a composite one; without any the id column is it.
+func buildModel(h *handle, t reflect.Type) *model {
+	m := &model{Type: t, byColumn: make(map[string]*field)}
+	fields := columnFields(t)
+	tagged := false
+	for _, sf := range fields {
+		if sf.Tag.Get("pri") != "" {
+			tagged = true
+		}
+	}
+	for _, sf := range fields {
+		column := columnOf(h, sf)
+		f := &field{
+			Name:       sf.Name,
+			Column:     column,
+			Index:      sf.Index,
+			Type:       sf.Type,
+			Tag:        sf.Tag,
+			Primary:    sf.Tag.Get("pri") != "" || (!tagged && column == "id"),
+			Composite:  sf.Tag.Get("composite"),
+			Serializer: sf.Tag.Get("serializer"),
+			Generated:  sf.Tag.Get("generated"),
+		}
+		m.Fields = append(m.Fields, f)
+		m.byColumn[column] = f
+	}
+	return m
+}
+
+func (m *model) field(column string) (*field, bool) {
+	f, ok := m.byColumn[column]
+	return f, ok
+}
+
+// primaryKey returns the primary key fields of m in declaration order.
+func (m *model) primaryKey() []*field {
+	var keys []*field
+	for _, f := range m.Fields {
+		if f.Primary {
+			keys = append(keys, f)
+		}
+	}
+	return keys
+}
+
+func resetModels() {
+	models.Range(func(key, _ any) bool {
+		models.Delete(key)
+		return true
+	})
+}
+
+// TableName returns the table T is mapped to.
+func TableName[T any]() string {
+	return getTableName(new(T))
+}
+
+// PrimaryColumn returns the primary key column of T, or "" if it has none.
+func PrimaryColumn[T any](db *sql.DB) string {
+	for _, f := range modelOf(lookupHandle(db), reflect.TypeOf(new(T)).Elem()).Fields {
+		if f.Primary {
+			return f.Column
+		}
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var ErrNamedParam = errors.New("orm: named parameter")
+
+// NamedArg binds the :name parameters of a statement.
+type NamedArg struct {
+	Name  string
+	Value any
+}
+
+// Named binds :name to value. Named and positional arguments can be mixed;
+// the positional ones keep $1..$n.
+//
+//	users, err := orm.Find[User](ctx, db, "status = :status AND age >= :age",
+//		orm.Named("status", "active"), orm.Named("age", 18))
+func Named(name string, value any) NamedArg {
+	return NamedArg{Name: name, Value: value}
+}
+
+type namedFrom struct {
+	v any
+}
+
+// NamedFrom binds :name parameters from a map[string]any or from the fields
+// of a struct, by column or field name.
+//
+//	n, err := orm.Delete[Session](ctx, db, "user_id = :user_id AND created_at < :created_at", orm.NamedFrom(session))
+func NamedFrom(v any) any {
+	return namedFrom{v: v}
+}
+
+// namedValue is a named argument with the cast its placeholder takes.
+type namedValue struct {
+	arg  any
+	cast string
+}
+
+// bindNamed rewrites the :name parameters of sqlStr into $N placeholders
+// numbered after the positional args, and returns the args to go with them.
+// Statements without named arguments are returned as they are; a :name
+// inside a string, a comment or a :: cast is left alone.
+func bindNamed(h *handle, sqlStr string, args []any) (string, []any, error) {
+	var values map[string]namedValue
+	var positional []any
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case NamedArg:
+			if values == nil {
+				values = make(map[string]namedValue)
+			}
+			values[arg.Name] = namedValue{arg: arg.Value}
+		case namedFrom:
+			if values == nil {
+				values = make(map[string]namedValue)
+			}
+			if err := namedValues(h, arg.v, values); err != nil {
+				return "", nil, err
+			}
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if values == nil {
+		return sqlStr, args, nil
+	}
+	var b strings.Builder
+	numbers := make(map[string]int)
+	tokens := lexSQL(sqlStr)
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.text != ":" || i+1 >= len(tokens) || tokens[i+1].kind != tokWord || (i > 0 && tokens[i-1].text == ":") {
+			b.WriteString(tok.text)
+			continue
+		}
+		name := tokens[i+1].text
+		v, ok := values[name]
+		if !ok {
+			return "", nil, fmt.Errorf("%w: line %d: no value for :%s", ErrNamedParam, tok.line, name)
+		}
+		n, ok := numbers[name]
+		if !ok {
+			positional = append(positional, v.arg)
+			n = len(positional)
+			numbers[name] = n
+		}
+		fmt.Fprintf(&b, "$%d%s", n, v.cast)
+		i++
+	}
+	return b.String(), positional, nil
+}
+
+// namedValues adds the entries of a map or the fields of a struct to values.
+func namedValues(h *handle, v any, values map[string]namedValue) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%w: %s keys are not strings", ErrNamedParam, rv.Type())
+		}
+		iter := rv.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = namedValue{arg: iter.Value().Interface()}
+		}
+	case reflect.Struct:
+		for _, sf := range columnFields(rv.Type()) {
+			arg, cast, skip, err := bindValue(sf, rv.FieldByIndex(sf.Index))
+			if err != nil {
+				return err
+			}
+			if skip {
+				continue
+			}
+			values[columnOf(h, sf)] = namedValue{arg: arg, cast: cast}
+			values[sf.Name] = namedValue{arg: arg, cast: cast}
+		}
+	default:
+		return fmt.Errorf("%w: cannot bind from %T", ErrNamedParam, v)
+	}
+	return nil
+}
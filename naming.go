@@ -0,0 +1,189 @@
+package orm
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+var (
+	snakeMu        sync.RWMutex
+	snakeCache     = make(map[string]string)
+	snakeOverrides = make(map[string]string)
+)
+
+// SetNameOverride forces the snake case form of a Go identifier, for names the
+// word splitter cannot guess (e.g. "IPv4Addr" -> "ipv4_addr").
+func SetNameOverride(name, snake string) {
+	snakeMu.Lock()
+	snakeOverrides[name] = snake
+	delete(snakeCache, name)
+	snakeMu.Unlock()
+	resetModels()
+}
+
+func resetSnakeCache() {
+	snakeMu.Lock()
+	defer snakeMu.Unlock()
+	snakeCache = make(map[string]string)
+}
+
+// ToSnake converts a Go identifier to its column form:
+// UserID -> user_id, HTTPCode -> http_code, Address2 -> address2, V2Name -> v2_name.
+func ToSnake(name string) string {
+	return toSnake(name)
+}
+
+func toSnake(name string) string {
+	snakeMu.RLock()
+	if s, ok := snakeOverrides[name]; ok {
+		snakeMu.RUnlock()
+		return s
+	}
+	if s, ok := snakeCache[name]; ok {
+		snakeMu.RUnlock()
+		return s
+	}
+	snakeMu.RUnlock()
+	s := convertSnake(name)
+	snakeMu.Lock()
+	snakeCache[name] = s
+	snakeMu.Unlock()
+	return s
+}
+
+func convertSnake(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	b.Grow(len(runes) + 4)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// ColumnMapper maps a Go field name to its column name. It is only consulted
+// for fields without an explicit column tag.
+type ColumnMapper func(goField string) string
+
+var (
+	mapperMu     sync.RWMutex
+	globalMapper ColumnMapper
+)
+
+// SetColumnMapper replaces the default snake case mapping for every handle
+// that has no mapper of its own. Passing nil restores the default.
+func SetColumnMapper(m ColumnMapper) {
+	mapperMu.Lock()
+	globalMapper = m
+	mapperMu.Unlock()
+	resetModels()
+}
+
+// SetHandleColumnMapper sets the column mapper used for queries issued
+// through db, taking precedence over the global mapper.
+func SetHandleColumnMapper(db *sql.DB, m ColumnMapper) {
+	handleOf(db).setMapper(m)
+}
+
+func mapColumn(h *handle, goField string) string {
+	if m := h.columnMapper(); m != nil {
+		return m(goField)
+	}
+	mapperMu.RLock()
+	m := globalMapper
+	mapperMu.RUnlock()
+	if m != nil {
+		return m(goField)
+	}
+	return toSnake(goField)
+}
+
+// columnOf returns the column of a struct field: the db tag, else the json
+// tag, else the mapped Go name. Tag options after a comma are ignored.
+func columnOf(h *handle, field reflect.StructField) string {
+	for _, key := range []string{"db", "json"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(key), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return mapColumn(h, field.Name)
+}
+
+// ignoredField reports fields without a column: unexported fields,
+// relations, fields tagged db:"-", and fields tagged json:"-" that carry no
+// db tag.
+func ignoredField(field reflect.StructField) bool {
+	if field.PkgPath != "" {
+		return true
+	}
+	if _, ok := relationOf(field); ok {
+		return true
+	}
+	if tag, ok := field.Tag.Lookup("db"); ok {
+		return tag == "-"
+	}
+	return field.Tag.Get("json") == "-"
+}
+
+// columnFields returns the fields of the struct t that map to columns, in
+// declaration order. Anonymous embedded structs are flattened, with Index
+// holding the path from t; as in Go, a shallower field hides a deeper one of
+// the same name.
+func columnFields(t reflect.Type) []reflect.StructField {
+	var all []reflect.StructField
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			sf.Index = append(append([]int(nil), index...), i)
+			if flattened(sf) {
+				walk(sf.Type, sf.Index)
+			} else if !ignoredField(sf) {
+				all = append(all, sf)
+			}
+		}
+	}
+	walk(t, nil)
+	depth := make(map[string]int)
+	for _, sf := range all {
+		if d, ok := depth[sf.Name]; !ok || len(sf.Index) < d {
+			depth[sf.Name] = len(sf.Index)
+		}
+	}
+	fields := all[:0]
+	for _, sf := range all {
+		if depth[sf.Name] == len(sf.Index) {
+			fields = append(fields, sf)
+			depth[sf.Name] = -1
+		}
+	}
+	return fields
+}
+
+// flattened reports anonymous struct fields whose fields are columns of the
+// outer struct. Embedded types that map to one column themselves (time.Time,
+// sql.Scanner or driver.Valuer implementations, converted types) and those
+// with a db tag are kept whole.
+func flattened(sf reflect.StructField) bool {
+	if !sf.Anonymous || sf.Type.Kind() != reflect.Struct {
+		return false
+	}
+	if _, ok := sf.Tag.Lookup("db"); ok {
+		return false
+	}
+	if _, ok := converterOf(sf.Type); ok {
+		return false
+	}
+	ptr := reflect.PointerTo(sf.Type)
+	return sf.Type != timeType && !ptr.Implements(scannerType) && !ptr.Implements(valuerType)
+}
@@ -0,0 +1,145 @@
+package orm
+
+import (
+	"reflect"
+)
+
+// QueryOption changes how a single call runs. Options are passed among the
+// args of Query (and the helpers built on it) and are removed before the
+// statement is sent to the database.
+type QueryOption interface {
+	applyQuery(o *queryOptions)
+}
+
+type queryOptions struct {
+	afterScan    []rowHook
+	tables       map[reflect.Type]string
+	progress     func(done int64)
+	settings     map[string]string
+	hints        []string
+	unscoped     bool
+	dedupe       *dedupeOption
+	sampleWeight string
+	preload      []string
+	hedged       bool
+	report       *BatchReport
+	columns      map[string]bool
+	skipZero     bool
+}
+
+type rowHook struct {
+	typ reflect.Type
+	fn  func(v reflect.Value) error
+}
+
+type queryOptionFunc func(o *queryOptions)
+
+func (f queryOptionFunc) applyQuery(o *queryOptions) {
+	f(o)
+}
+
+// AfterScan runs fn on every scanned row of type U before Query returns, for
+// decrypting or deriving fields. For Query[[]U] it runs once per element.
+func AfterScan[U any](fn func(row *U) error) QueryOption {
+	return queryOptionFunc(func(o *queryOptions) {
+		o.afterScan = append(o.afterScan, rowHook{
+			typ: reflect.TypeOf(new(U)).Elem(),
+			fn: func(v reflect.Value) error {
+				return fn(v.Addr().Interface().(*U))
+			},
+		})
+	})
+}
+
+// OnTable makes the call read or write table instead of the table T is
+// normally mapped to, for partitions and archive tables sharing one model.
+func OnTable[T any](table string) QueryOption {
+	return queryOptionFunc(func(o *queryOptions) {
+		if o.tables == nil {
+			o.tables = make(map[reflect.Type]string)
+		}
+		o.tables[reflect.TypeOf(new(T)).Elem()] = table
+	})
+}
+
+// OnProgress is called after every batch of a batched operation with the
+// number of rows processed so far.
+func OnProgress(fn func(done int64)) QueryOption {
+	return queryOptionFunc(func(o *queryOptions) {
+		o.progress = fn
+	})
+}
+
+func (o *queryOptions) reportProgress(done int64) {
+	if o.progress != nil {
+		o.progress(done)
+	}
+}
+
+// tableName returns the table of dest, honouring OnTable, quoted for the
+// dialect of h.
+func (o *queryOptions) tableName(h *handle, dest any) string {
+	t := reflect.TypeOf(dest)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if table, ok := o.tables[t]; ok {
+		return table
+	}
+	return quoteTable(h.sqlDialect(), rawTableName(dest))
+}
+
+func applyOptions(list []QueryOption) *queryOptions {
+	opts := &queryOptions{}
+	for _, opt := range list {
+		opt.applyQuery(opts)
+	}
+	return opts
+}
+
+// splitOptions separates query options from statement arguments.
+func splitOptions(args []any) ([]any, *queryOptions) {
+	opts := &queryOptions{}
+	var plain []any
+	for i, arg := range args {
+		opt, ok := arg.(QueryOption)
+		if !ok {
+			if plain != nil {
+				plain = append(plain, arg)
+			}
+			continue
+		}
+		if plain == nil {
+			plain = append(make([]any, 0, len(args)), args[:i]...)
+		}
+		opt.applyQuery(opts)
+	}
+	if plain == nil {
+		return args, opts
+	}
+	return plain, opts
+}
+
+// runAfterScan applies the row hooks to dest, a pointer to a row or to a
+// slice of rows.
+func (o *queryOptions) runAfterScan(dest any) error {
+	if len(o.afterScan) == 0 {
+		return nil
+	}
+	v := reflect.ValueOf(dest).Elem()
+	for _, hook := range o.afterScan {
+		switch {
+		case v.Type() == hook.typ:
+			if err := hook.fn(v); err != nil {
+				return err
+			}
+		case v.Kind() == reflect.Slice && v.Type().Elem() == hook.typ:
+			for i := 0; i < v.Len(); i++ {
+				if err := hook.fn(v.Index(i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package ormhttp
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gobkc/orm"
+)
+
+// Admin is a read-only browser over the models registered with orm.Register.
+// It is meant for development; always set Authorize when it is reachable by
+// anyone else.
+//
+//	GET /                 registered models
+//	GET /{model}          model mapping and the table schema
+//	GET /{model}/rows     rows, paginated with ?page=N and searched with ?q=
+//
+// {model} is the type name, or the package qualified name of LookupModel.
+type Admin struct {
+	DB        *sql.DB
+	Authorize func(r *http.Request) error
+	PageSize  int
+}
+
+// NewAdmin returns an Admin backed by db.
+func NewAdmin(db *sql.DB) *Admin {
+	return &Admin{DB: db, PageSize: 50}
+}
+
+type schemaColumn struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Nullable bool    `json:"nullable"`
+	Default  *string `json:"default"`
+}
+
+func (a *Admin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.Authorize != nil {
+		if err := a.Authorize(r); err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("admin is read-only"))
+		return
+	}
+	path := strings.Trim(r.URL.Path, "/")
+	if path == "" {
+		writeJSON(w, http.StatusOK, orm.Models())
+		return
+	}
+	// Qualified model names contain slashes, so only a trailing /rows is
+	// taken off.
+	name := strings.TrimSuffix(path, "/rows")
+	info, ok := orm.LookupModel(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("model %s is not registered", name))
+		return
+	}
+	if name == path {
+		a.schema(w, r, info)
+	} else {
+		a.rows(w, r, info)
+	}
+}
+
+func (a *Admin) schema(w http.ResponseWriter, r *http.Request, info orm.ModelInfo) {
+	schema, table := orm.SplitTable(info.Table)
+	rows, err := a.DB.QueryContext(r.Context(), `SELECT column_name, data_type, is_nullable = 'YES', column_default
+		FROM information_schema.columns
+		WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND table_name = $2
+		ORDER BY ordinal_position`, schema, table)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer rows.Close()
+	var columns []schemaColumn
+	for rows.Next() {
+		var c schemaColumn
+		if err = rows.Scan(&c.Name, &c.Type, &c.Nullable, &c.Default); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		columns = append(columns, c)
+	}
+	if err = rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"model": info, "schema": columns})
+}
+
+func (a *Admin) rows(w http.ResponseWriter, r *http.Request, info orm.ModelInfo) {
+	size := a.PageSize
+	if size <= 0 {
+		size = 50
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	sqlStr := "SELECT * FROM " + info.Table
+	var args []any
+	if q := r.URL.Query().Get("q"); q != "" {
+		var conds []string
+		for _, c := range info.Columns {
+			if c.GoType == "string" {
+				conds = append(conds, c.Column+" ILIKE $1")
+			}
+		}
+		if len(conds) > 0 {
+			sqlStr += " WHERE " + strings.Join(conds, " OR ")
+			args = append(args, "%"+q+"%")
+		}
+	}
+	var order []string
+	for _, c := range info.Columns {
+		if c.Primary {
+			order = append(order, c.Column)
+		}
+	}
+	if len(order) > 0 {
+		sqlStr += " ORDER BY " + strings.Join(order, ", ")
+	}
+	sqlStr += fmt.Sprintf(" LIMIT %d OFFSET %d", size, (page-1)*size)
+	rows, err := a.DB.QueryContext(r.Context(), sqlStr, args...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer rows.Close()
+	scanner := orm.NewRowsScanner(a.DB, rows)
+	list := []map[string]any{}
+	for rows.Next() {
+		row := make(map[string]any)
+		if err = scanner.MapScan(row); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		for k, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[k] = string(b)
+			}
+		}
+		list = append(list, row)
+	}
+	if err = rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"page": page, "page_size": size, "rows": list})
+}
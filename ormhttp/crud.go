@@ -0,0 +1,222 @@
+// Package ormhttp exposes orm models over HTTP for internal tools.
+package ormhttp
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gobkc/orm"
+)
+
+// Resource serves list/get/create/update/delete for the model T.
+type Resource[T any] struct {
+	DB *sql.DB
+	// Authorize is called before every request; an error answers 403.
+	Authorize func(r *http.Request) error
+	// Validate is called with the decoded body on create and update; an error
+	// answers 422.
+	Validate func(r *http.Request, row *T) error
+	// ID extracts the primary key from the request. It defaults to the last
+	// segment of the path, so mount the resource with http.StripPrefix.
+	ID func(r *http.Request) string
+}
+
+// New returns a Resource for T backed by db.
+func New[T any](db *sql.DB) *Resource[T] {
+	return &Resource[T]{DB: db}
+}
+
+// ServeHTTP routes GET/POST on the collection and GET/PUT/PATCH/DELETE on
+// /{id} to the matching handler.
+func (res *Resource[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	hasID := res.id(r) != ""
+	switch {
+	case r.Method == http.MethodGet && !hasID:
+		res.List().ServeHTTP(w, r)
+	case r.Method == http.MethodGet:
+		res.Get().ServeHTTP(w, r)
+	case r.Method == http.MethodPost && !hasID:
+		res.Create().ServeHTTP(w, r)
+	case (r.Method == http.MethodPut || r.Method == http.MethodPatch) && hasID:
+		res.Update().ServeHTTP(w, r)
+	case r.Method == http.MethodDelete && hasID:
+		res.Delete().ServeHTTP(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+// List answers the rows selected by JSON:API style query parameters.
+func (res *Resource[T]) List() http.Handler {
+	return res.guard(func(w http.ResponseWriter, r *http.Request) {
+		q, err := orm.ParseListQuery[T](res.DB, r.URL.Query())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		rows, err := orm.List[T](r.Context(), res.DB, q)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if rows == nil {
+			rows = []T{}
+		}
+		writeJSON(w, http.StatusOK, rows)
+	})
+}
+
+// Get answers a single row by primary key.
+func (res *Resource[T]) Get() http.Handler {
+	return res.guard(func(w http.ResponseWriter, r *http.Request) {
+		row, ok := res.load(w, r)
+		if ok {
+			writeJSON(w, http.StatusOK, row)
+		}
+	})
+}
+
+// Create inserts the decoded body and answers it with its new primary key.
+func (res *Resource[T]) Create() http.Handler {
+	return res.guard(func(w http.ResponseWriter, r *http.Request) {
+		row, ok := res.decode(w, r)
+		if !ok {
+			return
+		}
+		rows, err := orm.Insert(r.Context(), res.DB, []T{*row})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, rows[0])
+	})
+}
+
+// Update writes the decoded body to the row identified by the path. PATCH
+// changes only the fields present in the body; PUT replaces the row, except
+// for its primary key and read-only columns such as created_at.
+func (res *Resource[T]) Update() http.Handler {
+	return res.guard(func(w http.ResponseWriter, r *http.Request) {
+		current, ok := res.load(w, r)
+		if !ok {
+			return
+		}
+		row := new(T)
+		if r.Method == http.MethodPatch {
+			*row = *current
+		}
+		if err := json.NewDecoder(r.Body).Decode(row); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if r.Method != http.MethodPatch {
+			keep(row, current)
+		}
+		if !res.validate(w, r, row) {
+			return
+		}
+		where := fmt.Sprintf("%s = $1", orm.PrimaryColumn[T](res.DB))
+		if _, err := orm.Update(r.Context(), res.DB, []T{*row}, where, res.id(r)); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		res.Get().ServeHTTP(w, r)
+	})
+}
+
+// keep copies the primary key and read-only fields of current into row.
+func keep[T any](row, current *T) {
+	dst, src := reflect.ValueOf(row).Elem(), reflect.ValueOf(current).Elem()
+	for _, c := range orm.ModelOf[T]().Columns {
+		if c.Primary || c.ReadOnly {
+			dst.FieldByName(c.Field).Set(src.FieldByName(c.Field))
+		}
+	}
+}
+
+// Delete removes the row identified by the path.
+func (res *Resource[T]) Delete() http.Handler {
+	return res.guard(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := res.load(w, r); !ok {
+			return
+		}
+		where := fmt.Sprintf("%s = $1", orm.PrimaryColumn[T](res.DB))
+		if _, err := orm.Delete[T](r.Context(), res.DB, where, res.id(r)); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (res *Resource[T]) guard(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if res.Authorize != nil {
+			if err := res.Authorize(r); err != nil {
+				writeError(w, http.StatusForbidden, err)
+				return
+			}
+		}
+		next(w, r)
+	})
+}
+
+func (res *Resource[T]) id(r *http.Request) string {
+	if res.ID != nil {
+		return res.ID(r)
+	}
+	path := strings.Trim(r.URL.Path, "/")
+	if i := strings.LastIndexByte(path, '/'); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+// load reads the row identified by the path; soft deleted rows are not found.
+func (res *Resource[T]) load(w http.ResponseWriter, r *http.Request) (*T, bool) {
+	id := res.id(r)
+	row, err := orm.First[T](r.Context(), res.DB, fmt.Sprintf("%s = $1", orm.PrimaryColumn[T](res.DB)), id)
+	if errors.Is(err, orm.ErrNotFound) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s %s not found", orm.TableName[T](), id))
+		return nil, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	return row, true
+}
+
+func (res *Resource[T]) decode(w http.ResponseWriter, r *http.Request) (*T, bool) {
+	row := new(T)
+	if err := json.NewDecoder(r.Body).Decode(row); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+	return row, res.validate(w, r, row)
+}
+
+func (res *Resource[T]) validate(w http.ResponseWriter, r *http.Request, row *T) bool {
+	if res.Validate != nil {
+		if err := res.Validate(r, row); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err)
+			return false
+		}
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
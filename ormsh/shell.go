@@ -0,0 +1,388 @@
+// Package ormsh is an interactive query runner for debugging model mappings.
+// Each input line is a builder expression on a model registered with
+// orm.Register, raw SQL, or a backslash command:
+//
+//	> User.Where("age > $1", 18).OrderBy("name").Limit(5)
+//	> SELECT count(*) FROM users
+//	> \d User
+//
+// Builder results are scanned into the model, so they show what the mapping
+// actually reads; SQL results show the driver's values and their Go types.
+package ormsh
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gobkc/orm"
+)
+
+// Shell reads statements from In and prints their results to Out.
+type Shell struct {
+	DB  *sql.DB
+	In  io.Reader
+	Out io.Writer
+	// Prompt is printed before each line; empty means no prompt.
+	Prompt string
+}
+
+// Run reads and runs lines until In is exhausted or \q is entered. Errors of
+// single statements are printed and do not stop the shell.
+func (s *Shell) Run(ctx context.Context) error {
+	in := bufio.NewScanner(s.In)
+	for {
+		if s.Prompt != "" {
+			fmt.Fprint(s.Out, s.Prompt)
+		}
+		if !in.Scan() {
+			return in.Err()
+		}
+		line := strings.TrimSuffix(strings.TrimSpace(in.Text()), ";")
+		switch {
+		case line == "":
+		case line == `\q`:
+			return nil
+		default:
+			if err := s.run(ctx, line); err != nil {
+				fmt.Fprintln(s.Out, "error:", err)
+			}
+		}
+	}
+}
+
+func (s *Shell) run(ctx context.Context, line string) error {
+	if strings.HasPrefix(line, `\`) {
+		return s.command(line)
+	}
+	if expr, err := parser.ParseExpr(line); err == nil {
+		if q, ok, err := parseBuilder(expr); ok {
+			if err != nil {
+				return err
+			}
+			return s.find(ctx, q)
+		}
+	}
+	return s.raw(ctx, line)
+}
+
+const help = `User.Where("age > $1", 18).OrderBy("name DESC").Limit(10)
+    query a registered model; Select, Offset and ToSQL() work too
+SELECT ...    run raw SQL
+\models       list the registered models
+\d Model      show the mapping of a model
+\q            quit
+`
+
+func (s *Shell) command(line string) error {
+	fields := strings.Fields(line)
+	switch {
+	case fields[0] == `\models`:
+		w := tabwriter.NewWriter(s.Out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "MODEL\tTABLE")
+		for _, m := range orm.Models() {
+			fmt.Fprintf(w, "%s\t%s\n", m.Name, m.Table)
+		}
+		return w.Flush()
+	case fields[0] == `\d` && len(fields) == 2:
+		info, ok := orm.LookupModel(fields[1])
+		if !ok {
+			return fmt.Errorf("model %s is not registered", fields[1])
+		}
+		fmt.Fprintf(s.Out, "%s -> %s\n", info.Name, info.Table)
+		w := tabwriter.NewWriter(s.Out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "FIELD\tCOLUMN\tTYPE\tPRIMARY")
+		for _, c := range info.Columns {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%v\n", c.Field, c.Column, c.GoType, c.Primary)
+		}
+		return w.Flush()
+	case fields[0] == `\?` || fields[0] == `\h`:
+		_, err := io.WriteString(s.Out, help)
+		return err
+	}
+	return fmt.Errorf("unknown command %s, try \\?", fields[0])
+}
+
+// builderQuery is a parsed builder expression.
+type builderQuery struct {
+	model  orm.ModelInfo
+	q      orm.ListQuery
+	wheres []orm.Fragment
+	toSQL  bool
+}
+
+// parseBuilder reads a call chain such as User.Where("a = $1", 1).Limit(5).
+// ok is false when expr does not start with a registered model, so the line
+// is run as SQL instead.
+func parseBuilder(expr ast.Expr) (q *builderQuery, ok bool, err error) {
+	var calls []*ast.CallExpr
+	for {
+		call, isCall := expr.(*ast.CallExpr)
+		if !isCall {
+			break
+		}
+		sel, isSel := call.Fun.(*ast.SelectorExpr)
+		if !isSel {
+			return nil, false, nil
+		}
+		calls = append(calls, call)
+		expr = sel.X
+	}
+	ident, isIdent := expr.(*ast.Ident)
+	if !isIdent {
+		return nil, false, nil
+	}
+	info, registered := orm.LookupModel(ident.Name)
+	if !registered {
+		return nil, false, nil
+	}
+	q = &builderQuery{model: info}
+	for i := len(calls) - 1; i >= 0; i-- {
+		name := calls[i].Fun.(*ast.SelectorExpr).Sel.Name
+		args := make([]any, len(calls[i].Args))
+		for j, arg := range calls[i].Args {
+			if args[j], err = literal(arg); err != nil {
+				return nil, true, fmt.Errorf("%s: %v", name, err)
+			}
+		}
+		if err = q.apply(name, args); err != nil {
+			return nil, true, err
+		}
+	}
+	return q, true, nil
+}
+
+func (q *builderQuery) apply(name string, args []any) error {
+	if q.toSQL {
+		return errors.New("ToSQL must come last")
+	}
+	strs := func() ([]string, error) {
+		out := make([]string, len(args))
+		for i, arg := range args {
+			s, ok := arg.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s takes strings", name)
+			}
+			out[i] = s
+		}
+		return out, nil
+	}
+	var err error
+	switch name {
+	case "Where":
+		cond, ok := "", len(args) > 0
+		if ok {
+			cond, ok = args[0].(string)
+		}
+		if !ok {
+			return errors.New("Where takes a condition string and its arguments")
+		}
+		q.wheres = append(q.wheres, orm.Raw(cond, args[1:]...))
+	case "Select":
+		var columns []string
+		if columns, err = strs(); err == nil {
+			q.q.Columns = append(q.q.Columns, columns...)
+		}
+	case "OrderBy":
+		var terms []string
+		if terms, err = strs(); err == nil {
+			q.q.OrderBy = append(q.q.OrderBy, terms...)
+		}
+	case "Limit", "Offset":
+		n, ok := int64(0), len(args) == 1
+		if ok {
+			n, ok = args[0].(int64)
+		}
+		if !ok {
+			return fmt.Errorf("%s takes one integer", name)
+		}
+		if name == "Limit" {
+			q.q.Limit = int(n)
+		} else {
+			q.q.Offset = int(n)
+		}
+	case "ToSQL":
+		q.toSQL = true
+	default:
+		return fmt.Errorf("unknown builder step %s", name)
+	}
+	return err
+}
+
+// statement renders the query, renumbering the Where placeholders.
+func (q *builderQuery) statement() (string, []any, error) {
+	if len(q.wheres) > 0 {
+		slots := make([]string, len(q.wheres))
+		vars := orm.Vars{}
+		for i, w := range q.wheres {
+			name := "w" + strconv.Itoa(i)
+			slots[i] = "({{" + name + "}})"
+			vars[name] = w
+		}
+		where, args, err := orm.Template(strings.Join(slots, " AND ")).Render(vars)
+		if err != nil {
+			return "", nil, err
+		}
+		q.q.Where, q.q.Args = where, args
+	}
+	return q.q.SQL(q.model.Table), q.q.Args, nil
+}
+
+// literal converts a Go literal argument of a builder step.
+func literal(e ast.Expr) (any, error) {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.INT:
+			return strconv.ParseInt(e.Value, 0, 64)
+		case token.FLOAT:
+			return strconv.ParseFloat(e.Value, 64)
+		case token.STRING, token.CHAR:
+			return strconv.Unquote(e.Value)
+		}
+	case *ast.Ident:
+		switch e.Name {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "nil":
+			return nil, nil
+		}
+	case *ast.UnaryExpr:
+		if e.Op == token.SUB {
+			v, err := literal(e.X)
+			switch v := v.(type) {
+			case int64:
+				return -v, err
+			case float64:
+				return -v, err
+			}
+		}
+	case *ast.ParenExpr:
+		return literal(e.X)
+	}
+	return nil, errors.New("arguments must be literals")
+}
+
+func (s *Shell) find(ctx context.Context, q *builderQuery) error {
+	sqlStr, args, err := q.statement()
+	if err != nil {
+		return err
+	}
+	if q.toSQL {
+		fmt.Fprintln(s.Out, sqlStr)
+		for i, arg := range args {
+			fmt.Fprintf(s.Out, "  $%d = %#v\n", i+1, arg)
+		}
+		return nil
+	}
+	dest := reflect.New(reflect.SliceOf(q.model.Type))
+	if err = orm.SelectContext(ctx, s.DB, dest.Interface(), sqlStr, args...); err != nil {
+		return err
+	}
+	rows := dest.Elem()
+	w := tabwriter.NewWriter(s.Out, 0, 4, 2, ' ', 0)
+	header := make([]string, len(q.model.Columns))
+	for i, c := range q.model.Columns {
+		header[i] = fmt.Sprintf("%s (%s)", c.Column, c.GoType)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for i := 0; i < rows.Len(); i++ {
+		cells := make([]string, len(q.model.Columns))
+		for j, c := range q.model.Columns {
+			cells[j] = format(rows.Index(i).FieldByName(c.Field))
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.Out, "(%d rows)\n", rows.Len())
+	return nil
+}
+
+func (s *Shell) raw(ctx context.Context, sqlStr string) error {
+	rows, err := s.DB.QueryContext(ctx, sqlStr)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		fmt.Fprintln(s.Out, "ok")
+		return rows.Err()
+	}
+	scanner := orm.NewRowsScanner(s.DB, rows)
+	var table [][]any
+	for rows.Next() {
+		values, err := scanner.SliceScan()
+		if err != nil {
+			return err
+		}
+		table = append(table, values)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(s.Out, 0, 4, 2, ' ', 0)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c
+		if len(table) > 0 && table[0][i] != nil {
+			header[i] += fmt.Sprintf(" (%T)", table[0][i])
+		}
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, values := range table {
+		cells := make([]string, len(values))
+		for i, v := range values {
+			cells[i] = format(reflect.ValueOf(v))
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.Out, "(%d rows)\n", len(table))
+	return nil
+}
+
+// format renders a value for the result tables.
+func format(v reflect.Value) string {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return "NULL"
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return "NULL"
+	}
+	switch x := v.Interface().(type) {
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case []byte:
+		return string(x)
+	case fmt.Stringer:
+		return x.String()
+	}
+	if v.Kind() == reflect.String {
+		return strconv.Quote(v.String())
+	}
+	return fmt.Sprint(v.Interface())
+}
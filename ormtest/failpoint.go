@@ -0,0 +1,130 @@
+// Package ormtest provides helpers for testing code built on orm.
+package ormtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fault describes an artificial failure injected before a statement runs.
+type Fault struct {
+	// Match selects statements containing this substring; empty matches all.
+	Match string
+	// Delay is slept before the statement runs (or fails). It honours ctx.
+	Delay time.Duration
+	// Drop makes the statement fail with driver.ErrBadConn, which database/sql
+	// treats as a dropped connection.
+	Drop bool
+	// Code is a Postgres SQLSTATE (e.g. "40001") returned as a *PGError.
+	Code string
+	// Times limits how often the fault fires; zero fires forever.
+	Times int
+}
+
+// PGError mimics a server error carrying a SQLSTATE code.
+type PGError struct {
+	Code    string
+	Message string
+}
+
+func (e *PGError) Error() string {
+	return fmt.Sprintf("pq: %s (SQLSTATE %s)", e.Message, e.Code)
+}
+
+func (e *PGError) SQLState() string {
+	return e.Code
+}
+
+type failpoint struct {
+	Fault
+	fired int
+}
+
+// Failpoints injects faults into every connection opened through it. The zero
+// value is ready to use and safe for concurrent use.
+type Failpoints struct {
+	mu     sync.Mutex
+	points []*failpoint
+}
+
+// Add registers a fault. Faults are evaluated in the order they were added and
+// the first one that matches fires.
+func (f *Failpoints) Add(fault Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.points = append(f.points, &failpoint{Fault: fault})
+}
+
+// Reset removes every registered fault.
+func (f *Failpoints) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.points = nil
+}
+
+// Fired reports how many times faults matching the substring have fired.
+func (f *Failpoints) Fired(match string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, p := range f.points {
+		if p.Match == match {
+			n += p.fired
+		}
+	}
+	return n
+}
+
+// Wrap returns a connector whose statements pass through the failpoints.
+func (f *Failpoints) Wrap(c driver.Connector) driver.Connector {
+	return wrapConnector(c, f.intercept)
+}
+
+// Open is a shorthand for sql.OpenDB(f.Wrap(c)).
+func (f *Failpoints) Open(c driver.Connector) *sql.DB {
+	return sql.OpenDB(f.Wrap(c))
+}
+
+func (f *Failpoints) intercept(ctx context.Context, query string, _ []driver.NamedValue) error {
+	fault, ok := f.match(query)
+	if !ok {
+		return nil
+	}
+	if fault.Delay > 0 {
+		timer := time.NewTimer(fault.Delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if fault.Drop {
+		return driver.ErrBadConn
+	}
+	if fault.Code != "" {
+		return &PGError{Code: fault.Code, Message: "injected failure"}
+	}
+	return nil
+}
+
+func (f *Failpoints) match(query string) (Fault, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.points {
+		if p.Times > 0 && p.fired >= p.Times {
+			continue
+		}
+		if p.Match != "" && !strings.Contains(query, p.Match) {
+			continue
+		}
+		p.fired++
+		return p.Fault, true
+	}
+	return Fault{}, false
+}
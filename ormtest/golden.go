@@ -0,0 +1,124 @@
+package ormtest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gobkc/orm"
+)
+
+// UpdateEnv names the environment variable that rewrites golden files
+// instead of comparing against them, e.g. ORMTEST_UPDATE=1 go test ./...
+const UpdateEnv = "ORMTEST_UPDATE"
+
+// Statement is a single statement as it reached the driver.
+type Statement struct {
+	SQL  string
+	Args []any
+}
+
+// Recorder captures every statement sent through connections it wraps.
+type Recorder struct {
+	mu    sync.Mutex
+	stmts []Statement
+}
+
+// Wrap returns a connector that records statements before running them.
+func (r *Recorder) Wrap(c driver.Connector) driver.Connector {
+	return wrapConnector(c, r.record)
+}
+
+// Open is a shorthand for sql.OpenDB(r.Wrap(c)).
+func (r *Recorder) Open(c driver.Connector) *sql.DB {
+	return sql.OpenDB(r.Wrap(c))
+}
+
+// Statements returns a copy of the statements recorded so far.
+func (r *Recorder) Statements() []Statement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Statement(nil), r.stmts...)
+}
+
+// Reset discards recorded statements.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stmts = nil
+}
+
+func (r *Recorder) record(_ context.Context, query string, args []driver.NamedValue) error {
+	values := make([]any, len(args))
+	for i, nv := range args {
+		values[i] = nv.Value
+	}
+	r.mu.Lock()
+	r.stmts = append(r.stmts, Statement{SQL: query, Args: values})
+	r.mu.Unlock()
+	return nil
+}
+
+// AssertGolden compares the statements with testdata/<name>.golden. When
+// ORMTEST_UPDATE is set the golden file is (re)written instead.
+func AssertGolden(t testing.TB, name string, stmts []Statement) {
+	t.Helper()
+	got := FormatStatements(stmts)
+	path := filepath.Join("testdata", name+".golden")
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("ormtest: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("ormtest: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ormtest: %v (run with %s=1 to create it)", err, UpdateEnv)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("ormtest: generated SQL differs from %s\n--- want\n%s\n--- got\n%s", path, want, got)
+	}
+}
+
+// FormatStatements renders statements in the golden file format. SQL is
+// normalized with orm.FormatSQL so spacing changes do not break goldens.
+func FormatStatements(stmts []Statement) []byte {
+	var buf bytes.Buffer
+	for i, s := range stmts {
+		fmt.Fprintf(&buf, "-- %d\n%s\n", i+1, orm.FormatSQL(s.SQL))
+		if len(s.Args) > 0 {
+			args := make([]string, len(s.Args))
+			for j, a := range s.Args {
+				args[j] = formatArg(a)
+			}
+			fmt.Fprintf(&buf, "-- args: %s\n", strings.Join(args, ", "))
+		}
+	}
+	return buf.Bytes()
+}
+
+func formatArg(a any) string {
+	switch v := a.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []byte:
+		return fmt.Sprintf("%q", string(v))
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
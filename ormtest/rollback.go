@@ -0,0 +1,28 @@
+package ormtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gobkc/orm"
+)
+
+// errRollback makes orm.Transaction roll back after fn.
+var errRollback = errors.New("ormtest: rollback")
+
+// WithRollback runs fn inside a transaction that is rolled back when fn
+// returns, so tests can share one database without seeing each other's
+// writes. The executor passed to fn keeps db's settings (dialect, column
+// mapper, clock, session variables). When db is already a transaction, bound
+// with orm.Use or not, a savepoint is used instead of a new transaction.
+func WithRollback(t testing.TB, db orm.Executor, fn func(h orm.Executor)) {
+	t.Helper()
+	err := orm.Transaction(context.Background(), db, func(tx orm.Executor) error {
+		fn(tx)
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("ormtest: WithRollback: %v", err)
+	}
+}
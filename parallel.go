@@ -0,0 +1,105 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// MaxParallel bounds how many functions a single Parallel call runs at once.
+var MaxParallel = 8
+
+// Parallel runs fns concurrently, at most MaxParallel at a time. The first
+// error cancels the context given to the others and is returned once every
+// started function has finished.
+func Parallel(ctx context.Context, fns ...func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	limit := MaxParallel
+	if limit <= 0 {
+		limit = len(fns)
+	}
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		slots    = make(chan struct{}, limit)
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	for _, fn := range fns {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			fail(ctx.Err())
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(fn func(ctx context.Context) error) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			if err := fn(ctx); err != nil {
+				fail(err)
+			}
+		}(fn)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// SetMaxParallel caps the number of Fetch calls running against db at once,
+// across every Parallel call, so aggregate endpoints cannot drain its pool.
+// Zero removes the cap.
+func SetMaxParallel(db *sql.DB, n int) {
+	h := handleOf(db)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.parallel = nil
+	if n > 0 {
+		h.parallel = make(chan struct{}, n)
+	}
+}
+
+func (h *handle) parallelSlots() chan struct{} {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.parallel
+}
+
+// Fetch returns a function for Parallel running Query[T] into dest:
+//
+//	var users []User
+//	var total int64
+//	err := orm.Parallel(ctx,
+//		orm.Fetch(db, &users, "SELECT * FROM users LIMIT 10"),
+//		orm.Fetch(db, &total, "SELECT COUNT(*) FROM users"),
+//	)
+func Fetch[T any](db Executor, dest *T, sqlStr string, args ...any) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if slots := handleFor(route[T](db)).parallelSlots(); slots != nil {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		v, err := Query[T](ctx, db, sqlStr, args...)
+		if err != nil {
+			return err
+		}
+		*dest = *v
+		return nil
+	}
+}
@@ -0,0 +1,35 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// SkipZero makes Update leave columns alone whose field holds its zero
+// value, so rows only partly loaded or filled in can be written back. Fields
+// tagged orm:"forceNull" are still set to NULL.
+func SkipZero() QueryOption {
+	return queryOptionFunc(func(o *queryOptions) {
+		o.skipZero = true
+	})
+}
+
+// UpdateColumns writes only cols of rows, each matched by its primary key.
+// cols are column or field names. It returns the number of rows affected.
+//
+//	n, err := orm.UpdateColumns(ctx, db, []User{user}, "email", "UpdatedAt")
+func UpdateColumns[T any](ctx context.Context, db Executor, rows []T, cols ...string) (int64, error) {
+	m := modelOf(handleFor(route[T](db)), reflect.TypeOf(new(T)).Elem())
+	columns := make(map[string]bool, len(cols))
+	for _, name := range cols {
+		f, ok := m.selectable(name)
+		if !ok {
+			return 0, fmt.Errorf("%w: unknown field %q", ErrFilter, name)
+		}
+		columns[f.Column] = true
+	}
+	return Update(ctx, db, rows, "", queryOptionFunc(func(o *queryOptions) {
+		o.columns = columns
+	}))
+}
@@ -3,37 +3,82 @@ package orm
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"fmt"
 	"log"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
-var allows = []reflect.Kind{reflect.Struct, reflect.Slice, reflect.Int, reflect.Int64, reflect.String, reflect.Float64}
-var ErrAllow = fmt.Errorf("query: allow list: reflect.Struct/reflect.Slice/reflect.Int/reflect.Int64/reflect.String/reflect.Float64")
+var allows = []reflect.Kind{reflect.Struct, reflect.Slice, reflect.Map, reflect.Int, reflect.Int64, reflect.String, reflect.Float64}
+var ErrAllow = fmt.Errorf("query: allow list: reflect.Struct/reflect.Slice/reflect.Map/reflect.Int/reflect.Int64/reflect.String/reflect.Float64")
 var ErrInsertAllow = fmt.Errorf("query: allow list: reflect.Struct")
 var ErrUpdateAllow = ErrInsertAllow
+var ErrDeleteHooks = fmt.Errorf("orm: delete hooks need a where clause, not a DELETE statement")
 
-func Query[T any](ctx context.Context, db *sql.DB, sqlStr string, args ...any) (t *T, err error) {
+// Query runs sqlStr on db, which may be a *sql.DB or a caller managed
+// *sql.Tx (see Use), and scans the result into T: a struct, a number or
+// string, a map[string]any keyed by column for ad-hoc result shapes, or a
+// slice of structs, maps or single-column values such as []int64.
+//
+// sqlStr runs as written: soft deleted rows are only left out by the helpers
+// that build their own SELECT (Find, First, Count, Paginate, List and the
+// builder), so raw queries on soft deleting models filter them themselves.
+func Query[T any](ctx context.Context, db Executor, sqlStr string, args ...any) (t *T, err error) {
+	db = route[T](db)
 	t = new(T)
-	sqlStr, args = parseSqlIn(sqlStr, args)
-	defer outputSql(sqlStr, args)
-	stmt, err := db.PrepareContext(ctx, sqlStr)
-	if err != nil {
+	args, opts := splitOptions(args)
+	h := handleFor(db)
+	if sqlStr, args, err = bindNamed(h, sqlStr, args); err != nil {
 		return nil, err
 	}
-	rows, err := stmt.QueryContext(ctx, args...)
+	sqlStr, args = parseSqlIn(opts.hinted(sqlStr), args)
+	defer outputTimed(sqlStr, args, time.Now())
+	ex := db
+	if settings := opts.plannerSettings(t); len(settings) > 0 || len(h.sessionSettings(ctx)) > 0 {
+		tx, txErr := beginTx(ctx, db)
+		if txErr != nil {
+			return nil, txErr
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			err = tx.Commit()
+		}()
+		if err = applySettings(ctx, h, tx, settings); err != nil {
+			return nil, err
+		}
+		ex = tx
+	}
+	query, queryArgs := rebind(h.sqlDialect(), sqlStr, args)
+	if hedge := opts.hedging(h, ex); hedge != nil {
+		t, err = hedgedRead(ctx, ex, hedge, func(ctx context.Context, ex Executor, dest *T) error {
+			return scanQuery(ctx, ex, h, query, queryArgs, dest)
+		})
+	} else {
+		err = scanQuery(ctx, ex, h, query, queryArgs, t)
+	}
 	if err != nil {
+		return nil, wrapQueryError(err, sqlStr, "")
+	}
+	shadowQuery(h, sqlStr, args, t)
+	if err = preload(ctx, ex, h, t, opts); err != nil {
 		return nil, err
 	}
-	defer func() {
-		if rows != nil {
-			rows.Close()
-		}
-	}()
+	if err = opts.runAfterScan(t); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// scanQuery runs query on ex and scans the result into t.
+func scanQuery[T any](ctx context.Context, ex Executor, h *handle, query string, args []any, t *T) error {
 	kind := reflect.TypeOf(t).Elem().Kind()
 	pass := false
 	for _, allow := range allows {
@@ -43,11 +88,21 @@ func Query[T any](ctx context.Context, db *sql.DB, sqlStr string, args ...any) (
 		}
 	}
 	if !pass {
-		return nil, ErrAllow
+		return ErrAllow
 	}
+	stmt, err := ex.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 	var unmarshalMap = map[reflect.Kind]func() error{
 		reflect.Struct: func() error {
-			return unmarshalStruct(rows, t)
+			return unmarshalStruct(h, rows, t)
 		},
 		reflect.Int: func() error {
 			return unmarshalNumOrStr(rows, t)
@@ -62,212 +117,439 @@ func Query[T any](ctx context.Context, db *sql.DB, sqlStr string, args ...any) (
 			return unmarshalNumOrStr(rows, t)
 		},
 		reflect.Slice: func() error {
-			return unmarshalSlice(rows, t)
+			return unmarshalSlice(h, rows, t)
+		},
+		reflect.Map: func() error {
+			return unmarshalMapRow(rows, t)
 		},
 	}
-	if err = unmarshalMap[kind](); err != nil {
-		return
-	}
-	return
+	return unmarshalMap[kind]()
 }
 
-func Insert[T any](ctx context.Context, db *sql.DB, dest []T) (newDest []T, err error) {
+// Insert writes dest in one transaction; inside a caller's *sql.Tx a
+// savepoint is used instead.
+func Insert[T any](ctx context.Context, db Executor, dest []T, options ...QueryOption) (newDest []T, err error) {
+	db = route[T](db)
 	t := new(T)
 	typeOf := reflect.TypeOf(t).Elem()
 	if typeOf.Kind() == reflect.Pointer {
 		err = ErrInsertAllow
 		return
 	}
-	tableName := getTableName(t)
-	var fields string
-	var values string
-	tx, err := db.Begin()
+	opts := applyOptions(options)
+	h := handleFor(db)
+	tableName := opts.tableName(h, t)
+	if dest, err = dedupeRows(h, dest, opts.dedupe); err != nil {
+		return nil, err
+	}
+	mirror := mirrorOf(h, tableName)
+	tx, err := beginTx(ctx, db)
 	if err != nil {
 		return nil, err
 	}
-	for _, row := range dest {
-		kv := getKeysValues(row)
-		fields = kv.Key
-		values = fmt.Sprintf(`(%s)`, kv.Value)
-		sqlStr := fmt.Sprintf(`INSERT INTO %s(%s) VALUES %s RETURNING id`, tableName, fields, values)
-		outputSql(sqlStr, nil)
-		stmt, err := tx.Prepare(sqlStr)
+	for i, row := range dest {
+		written, err := writeRow(ctx, tx, opts.report, i, row, func(ex Executor) error {
+			return insertRow(ctx, ex, h, tableName, mirror, &row)
+		})
 		if err != nil {
 			tx.Rollback()
 			return nil, err
 		}
-		var lastId int64
-		if err = stmt.QueryRowContext(ctx).Scan(&lastId); err != nil {
-			return nil, err
+		if written {
+			newDest = append(newDest, row)
 		}
-		//only for mysql
-		//lastId, err := result.LastInsertId()
-		//if err != nil {
-		//	return nil, err
-		//}
-		savePrimaryKey(&row, lastId)
-		newDest = append(newDest, row)
 	}
-	if err = tx.Commit(); err != nil {
+	if err = mirror.commit(ctx, db, tx.Commit); err != nil {
+		tx.Rollback()
 		return nil, err
 	}
 	return
 }
 
-func Update[T any](ctx context.Context, db *sql.DB, dest []T, where string, args ...any) error {
+// insertRow writes one row of Insert on ex, filling its database generated
+// keys.
+func insertRow[T any](ctx context.Context, ex Executor, h *handle, tableName string, mirror *mirror, row *T) error {
+	if err := beforeInsert(ctx, row); err != nil {
+		return err
+	}
+	stampTimes(h, row, false)
+	if _, err := assignIDs(h, row); err != nil {
+		return err
+	}
+	returned := returnedKeys(h, row)
+	kv, err := getKeysValues(h, *row)
+	if err != nil {
+		return err
+	}
+	sqlStr := fmt.Sprintf(`INSERT INTO %s(%s) VALUES (%s)`, tableName, kv.Key, kv.Value)
+	start := time.Now()
+	mirrorSql, mirrorArgs := sqlStr, kv.Args
+	if len(returned) == 0 {
+		query, args := rebind(h.sqlDialect(), sqlStr, kv.Args)
+		if _, err = ex.ExecContext(ctx, query, args...); err != nil {
+			return wrapQueryError(err, sqlStr, tableName)
+		}
+		outputTimed(sqlStr, kv.Args, start)
+	} else {
+		valueOf := reflect.ValueOf(row).Elem()
+		if h.sqlDialect().Returning() {
+			columns := make([]string, len(returned))
+			targets := make([]any, len(returned))
+			for i, f := range returned {
+				columns[i] = f.Column
+				targets[i] = valueOf.FieldByIndex(f.Index).Addr().Interface()
+			}
+			sqlStr += ` RETURNING ` + strings.Join(columns, ", ")
+			query, args := rebind(h.sqlDialect(), sqlStr, kv.Args)
+			if err = ex.QueryRowContext(ctx, query, args...).Scan(targets...); err != nil {
+				return wrapQueryError(err, sqlStr, tableName)
+			}
+		} else {
+			query, args := rebind(h.sqlDialect(), sqlStr, kv.Args)
+			result, err := ex.ExecContext(ctx, query, args...)
+			if err != nil {
+				return wrapQueryError(err, sqlStr, tableName)
+			}
+			lastId, err := result.LastInsertId()
+			if err != nil {
+				return err
+			}
+			savePrimaryKey(h, row, lastId)
+		}
+		outputTimed(sqlStr, kv.Args, start)
+		mirrorArgs = append([]any{}, kv.Args...)
+		mirrorFields, mirrorValues := kv.Key, kv.Value
+		for _, f := range returned {
+			mirrorArgs = append(mirrorArgs, valueOf.FieldByIndex(f.Index).Interface())
+			mirrorFields += "," + f.Column
+			mirrorValues += fmt.Sprintf(",$%d", len(mirrorArgs))
+		}
+		mirrorSql = fmt.Sprintf(`INSERT INTO %s(%s) VALUES (%s)`, tableName, strings.TrimPrefix(mirrorFields, ","), strings.TrimPrefix(mirrorValues, ","))
+	}
+	if err = applyRollups(ctx, ex, h, row); err != nil {
+		return err
+	}
+	if err = afterInsert(ctx, row); err != nil {
+		return err
+	}
+	mirrorSql, mirrorArgs = rebind(h.sqlDialect(), mirrorSql, mirrorArgs)
+	mirror.add(mirrorSql, mirrorArgs...)
+	return nil
+}
+
+// Update writes dest, each row matched by where or else by its primary key,
+// in one transaction and returns the number of rows affected.
+func Update[T any](ctx context.Context, db Executor, dest []T, where string, args ...any) (int64, error) {
+	db = route[T](db)
 	t := new(T)
 	typeOf := reflect.TypeOf(t).Elem()
 	if typeOf.Kind() == reflect.Pointer {
-		return ErrUpdateAllow
+		return 0, ErrUpdateAllow
 	}
-	tx, err := db.Begin()
+	args, opts := splitOptions(args)
+	h := handleFor(db)
+	tableName := opts.tableName(h, t)
+	where, args, err := bindNamed(h, where, args)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	for _, row := range dest {
-		rowSql := generateUpdate(where, row)
-		stmt, err := tx.Prepare(rowSql)
-		if err != nil {
-			tx.Rollback()
+	mirror := mirrorOf(h, tableName)
+	tx, err := beginTx(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	var affected int64
+	for i, row := range dest {
+		var n int64
+		written, err := writeRow(ctx, tx, opts.report, i, row, func(ex Executor) (err error) {
+			n, err = updateRow(ctx, ex, h, tableName, mirror, where, args, opts, &row)
 			return err
-		}
-		outputSql(rowSql, args)
-		_, err = stmt.ExecContext(ctx, args...)
+		})
 		if err != nil {
 			tx.Rollback()
-			return err
+			return 0, err
+		}
+		if written {
+			affected += n
 		}
 	}
-	if err = tx.Commit(); err != nil {
-		return err
+	if err = mirror.commit(ctx, db, tx.Commit); err != nil {
+		tx.Rollback()
+		return 0, err
 	}
-	return nil
+	return affected, nil
 }
 
-func Exec(ctx context.Context, db *sql.DB, sqlStr string, args ...any) error {
-	stmt, err := db.PrepareContext(ctx, sqlStr)
+// updateRow writes one row of Update on ex and returns the rows affected.
+func updateRow[T any](ctx context.Context, ex Executor, h *handle, tableName string, mirror *mirror, where string, args []any, opts *queryOptions, row *T) (int64, error) {
+	if err := beforeUpdate(ctx, row); err != nil {
+		return 0, err
+	}
+	stampTimes(h, row, true)
+	rowSql, setArgs, err := generateUpdate(h, tableName, where, len(args), *row, opts)
+	if err != nil || rowSql == "" {
+		return 0, err
+	}
+	rowArgs := append(append([]any{}, args...), setArgs...)
+	start := time.Now()
+	query, queryArgs := rebind(h.sqlDialect(), rowSql, rowArgs)
+	result, err := ex.ExecContext(ctx, query, queryArgs...)
+	if err != nil {
+		return 0, wrapQueryError(err, rowSql, tableName)
+	}
+	outputTimed(rowSql, rowArgs, start)
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if err = afterUpdate(ctx, row); err != nil {
+		return 0, err
+	}
+	mirror.add(query, queryArgs...)
+	return n, nil
+}
+
+func Exec(ctx context.Context, db Executor, sqlStr string, args ...any) error {
+	h := handleFor(db)
+	sqlStr, args, err := bindNamed(h, sqlStr, args)
 	if err != nil {
 		return err
 	}
-	_, err = stmt.ExecContext(ctx, args...)
-	return err
+	defer outputTimed(sqlStr, args, time.Now())
+	query, queryArgs := rebind(h.sqlDialect(), sqlStr, args)
+	_, err = db.ExecContext(ctx, query, queryArgs...)
+	return wrapQueryError(err, sqlStr, "")
 }
 
-func Delete[T any](ctx context.Context, db *sql.DB, where string, args ...any) error {
+// Delete removes the rows of T's table matching where, or marks them
+// deleted when T soft deletes, and returns the number of rows affected.
+// When T has delete hooks, the matching rows are read first in the same
+// transaction and the hooks run on each of them; where must then be a
+// condition, as a full DELETE statement fails with ErrDeleteHooks.
+func Delete[T any](ctx context.Context, db Executor, where string, args ...any) (int64, error) {
+	db = route[T](db)
+	var t any = new(T)
+	if reflect.TypeOf(t).Elem().Kind() == reflect.Pointer {
+		return 0, ErrInsertAllow
+	}
+	_, before := t.(BeforeDeleter)
+	_, after := t.(AfterDeleter)
+	if !before && !after {
+		return deleteWhere[T](ctx, db, where, args)
+	}
+	if isDeleteStatement(where) {
+		return 0, ErrDeleteHooks
+	}
+	var n int64
+	err := Transaction(ctx, db, func(tx Executor) error {
+		rows, err := Find[T](ctx, tx, where, args...)
+		if err != nil {
+			return err
+		}
+		for i := range rows {
+			if err = beforeDelete(ctx, &rows[i]); err != nil {
+				return err
+			}
+		}
+		if n, err = deleteWhere[T](ctx, tx, where, args); err != nil {
+			return err
+		}
+		for i := range rows {
+			if err = afterDelete(ctx, &rows[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+// deleteWhere runs the DELETE, or soft delete UPDATE, of Delete.
+func deleteWhere[T any](ctx context.Context, db Executor, where string, args []any) (int64, error) {
 	t := new(T)
-	typeOf := reflect.TypeOf(t).Elem()
-	if typeOf.Kind() == reflect.Pointer {
-		return ErrInsertAllow
+	args, opts := splitOptions(args)
+	h := handleFor(db)
+	tableName := opts.tableName(h, t)
+	where, args, err := bindNamed(h, where, args)
+	if err != nil {
+		return 0, err
 	}
-	where = generateDelete(where, t)
 	where, args = parseSqlIn(where, args)
-	defer outputSql(where, args)
-	stmt, err := db.PrepareContext(ctx, where)
-	if err != nil {
-		return err
+	if update, updateArgs := softDelete[T](h, tableName, where, args, opts); update != "" {
+		where, args = update, updateArgs
+	} else {
+		where = generateDelete(tableName, where)
+	}
+	defer outputTimed(where, args, time.Now())
+	where, args = rebind(h.sqlDialect(), where, args)
+	var result sql.Result
+	if mirror := mirrorOf(h, tableName); mirror != nil {
+		result, err = mirror.exec(ctx, db, where, args...)
+	} else {
+		result, err = db.ExecContext(ctx, where, args...)
 	}
-	_, err = stmt.QueryContext(ctx, args...)
 	if err != nil {
-		return err
+		return 0, wrapQueryError(err, where, tableName)
 	}
-	return nil
+	return result.RowsAffected()
 }
 
-func unmarshalStruct(rows *sql.Rows, dest any) error {
+func unmarshalStruct(h *handle, rows *sql.Rows, dest any) error {
 	columns, err := rows.Columns()
 	if err != nil {
 		return err
 	}
-	var values []any
-	var fieldNames []string
-	var fieldsMap = make(map[string]int)
-	typeOf := reflect.TypeOf(dest).Elem()
 	valueOf := reflect.ValueOf(dest).Elem()
-	for curField := 0; curField < valueOf.NumField(); curField++ {
-		fName := typeOf.Field(curField).Name
-		tag := typeOf.Field(curField).Tag.Get("json")
-		if tag == "" {
-			tag = toSnake(fName)
-		}
-		fieldsMap[tag] = curField
-	}
-	for _, column := range columns {
-		if curField, ok := fieldsMap[column]; ok {
-			field := valueOf.Field(curField)
-			fName := typeOf.Field(curField).Name
-			fieldNames = append(fieldNames, fName)
-			values = append(values, reflect.New(field.Type()).Interface())
-		}
-	}
+	plan := newScanPlan(modelOf(h, valueOf.Type()), columns)
+	found := false
 	for rows.Next() {
-		err = rows.Scan(values...)
-		if err != nil {
+		if err = plan.scan(rows, valueOf); err != nil {
 			return err
 		}
+		found = true
 	}
-	for i, column := range fieldNames {
-		v := reflect.ValueOf(values[i]).Elem()
-		reflect.ValueOf(dest).Elem().FieldByName(column).Set(v)
+	if err = rows.Err(); err != nil || found {
+		return err
 	}
-	return nil
+	return ErrNotFound
 }
 
-func unmarshalSlice(rows *sql.Rows, dest any) error {
-	var values []any
-	var fieldNames []string
-	var fieldsMap = make(map[string]int)
+func unmarshalSlice(h *handle, rows *sql.Rows, dest any) error {
 	columns, err := rows.Columns()
 	if err != nil {
 		return err
 	}
-	destType := reflect.Indirect(reflect.ValueOf(dest).Elem()).Type()
-	valueElem := reflect.New(destType.Elem())
-	meta := valueElem.Interface()
-	typeOf := reflect.TypeOf(meta).Elem()
-	valueOf := reflect.ValueOf(meta).Elem()
-	for curField := 0; curField < valueOf.NumField(); curField++ {
-		fName := typeOf.Field(curField).Name
-		tag := typeOf.Field(curField).Tag.Get("json")
-		if tag == "" {
-			tag = toSnake(fName)
+	sliceOf := reflect.ValueOf(dest).Elem()
+	elemType := sliceOf.Type().Elem()
+	if elemType.Kind() == reflect.Map {
+		if !isRowMap(elemType) {
+			return ErrAllow
+		}
+		for rows.Next() {
+			row, err := scanRowMap(rows, columns)
+			if err != nil {
+				return err
+			}
+			sliceOf.Set(reflect.Append(sliceOf, reflect.ValueOf(row).Convert(elemType)))
 		}
-		fieldsMap[tag] = curField
+		return rows.Err()
 	}
-	for _, column := range columns {
-		if curField, ok := fieldsMap[column]; ok {
-			field := valueOf.Field(curField)
-			fName := typeOf.Field(curField).Name
-			fieldNames = append(fieldNames, fName)
-			values = append(values, reflect.New(field.Type()).Interface())
+	if isScalar(elemType) {
+		if len(columns) != 1 {
+			return fmt.Errorf("scan: []%s needs one column, got %d", elemType, len(columns))
 		}
+		for rows.Next() {
+			elem := reflect.New(elemType).Elem()
+			if err = scanScalar(rows, elem); err != nil {
+				return err
+			}
+			sliceOf.Set(reflect.Append(sliceOf, elem))
+		}
+		return rows.Err()
 	}
-	var out reflect.Value
+	plan := newScanPlan(modelOf(h, elemType), columns)
 	for rows.Next() {
-		scanRowValues := values
-		err = rows.Scan(scanRowValues...)
-		if err != nil {
+		elem := reflect.New(elemType).Elem()
+		if err = plan.scan(rows, elem); err != nil {
 			return err
 		}
-		newMeta := meta
-		for i, column := range fieldNames {
-			v := reflect.ValueOf(values[i]).Elem()
-			reflect.ValueOf(newMeta).Elem().FieldByName(column).Set(v)
+		sliceOf.Set(reflect.Append(sliceOf, elem))
+	}
+	return rows.Err()
+}
+
+// isScalar reports whether a column scans straight into t: anything but a
+// struct, and structs such as time.Time or sql.NullString that scan
+// themselves.
+func isScalar(t reflect.Type) bool {
+	if _, ok := converterOf(t); ok {
+		return true
+	}
+	return t.Kind() != reflect.Struct || t == timeType || reflect.PointerTo(t).Implements(scannerType)
+}
+
+// scanScalar scans the single column of the current row into dest, through
+// the converter registered for its type if any.
+func scanScalar(rows *sql.Rows, dest reflect.Value) error {
+	conv, ok := converterOf(dest.Type())
+	if !ok {
+		return rows.Scan(dest.Addr().Interface())
+	}
+	var src any
+	if err := rows.Scan(&src); err != nil || src == nil {
+		return err
+	}
+	return conv.decode(src, dest)
+}
+
+// unmarshalMapRow scans the first row into dest, a *map[string]any keyed by
+// column name.
+func unmarshalMapRow(rows *sql.Rows, dest any) error {
+	mapOf := reflect.ValueOf(dest).Elem()
+	if !isRowMap(mapOf.Type()) {
+		return ErrAllow
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return err
 		}
-		out = reflect.Append(reflect.ValueOf(dest).Elem(), reflect.ValueOf(newMeta).Elem())
-		reflect.ValueOf(dest).Elem().Set(out)
+		return ErrNotFound
+	}
+	row, err := scanRowMap(rows, columns)
+	if err != nil {
+		return err
 	}
+	mapOf.Set(reflect.ValueOf(row).Convert(mapOf.Type()))
 	return nil
 }
 
+// isRowMap reports whether t can hold a row keyed by column name, like
+// map[string]any.
+func isRowMap(t reflect.Type) bool {
+	return t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.Interface && t.Elem().NumMethod() == 0
+}
+
+// scanRowMap scans the current row keyed by column name. []byte values, as
+// drivers return for numeric and text-like types, become strings.
+func scanRowMap(rows *sql.Rows, columns []string) (map[string]any, error) {
+	values := make([]any, len(columns))
+	targets := make([]any, len(columns))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+	if err := rows.Scan(targets...); err != nil {
+		return nil, err
+	}
+	row := make(map[string]any, len(columns))
+	for i, column := range columns {
+		if b, ok := values[i].([]byte); ok {
+			values[i] = string(b)
+		}
+		row[column] = values[i]
+	}
+	return row, nil
+}
+
 func unmarshalNumOrStr(rows *sql.Rows, dest any) error {
-	for {
-		rows.Next()
-		return rows.Scan(dest)
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return ErrNotFound
 	}
-	return nil
+	return rows.Scan(dest)
 }
 
 func getTableName(dest any) string {
+	return QuoteTable(rawTableName(dest))
+}
+
+// rawTableName returns the unquoted table name of dest.
+func rawTableName(dest any) string {
 	var tableName string
 	valueOf := reflect.ValueOf(dest)
 	typeOf := reflect.TypeOf(dest)
@@ -281,6 +563,9 @@ func getTableName(dest any) string {
 			tableName = retList[0].String()
 		}
 	}
+	if typeOf.Kind() == reflect.Struct {
+		tableName = taggedTable(typeOf, tableName)
+	}
 	if tableName == `` {
 		tableName = toSnake(typeOf.Name())
 	}
@@ -290,242 +575,429 @@ func getTableName(dest any) string {
 type KV struct {
 	Key   string
 	Value string
+	Args  []any
 }
 
-func getKeysValues(dest any) *KV {
+func getKeysValues(h *handle, dest any) (*KV, error) {
 	typeOf := reflect.TypeOf(dest)
 	valueOf := reflect.ValueOf(dest)
 	if typeOf.Kind() == reflect.Pointer {
 		typeOf = typeOf.Elem()
 		valueOf = valueOf.Elem()
 	}
+	m := modelOf(h, typeOf)
 	var keys, values []string
-	for cur := 0; cur < typeOf.NumField(); cur++ {
-		var name string
-		if js := typeOf.Field(cur).Tag.Get("json"); js != "" {
-			name = js
-		} else {
-			name = toSnake(typeOf.Field(cur).Name)
+	var args []any
+	for _, sf := range columnFields(typeOf) {
+		fv := valueOf.FieldByIndex(sf.Index)
+		name := columnOf(h, sf)
+		if f, _ := m.field(name); f.Primary && fv.IsZero() {
+			continue
 		}
-		if name == "id" || typeOf.Field(cur).Tag.Get("pri") != "" {
+		if sf.Tag.Get("generated") != "" {
 			continue
 		}
-		value := valueOf.Field(cur).Interface()
-		if value == nil {
-			value = ``
-		}
-		var strValue = fmt.Sprintf("%v", value)
-		valueKind := reflect.TypeOf(value).Kind()
-		if valueKind == reflect.String || valueKind == reflect.Interface {
-			strValue = fmt.Sprintf("'%v'", value)
-		}
-		if valueKind == reflect.Struct {
-			if t, ok := value.(time.Time); ok {
-				if t.IsZero() {
-					strValue = "DEFAULT"
-				} else {
-					strValue = t.Format(`'2006-01-02 15:04:05'`)
-				}
-			}
+		switch zeroIntent(sf.Tag, fv) {
+		case zeroOmit:
+			continue
+		case zeroNull:
+			args = append(args, nil)
+			keys = append(keys, name)
+			values = append(values, fmt.Sprintf("$%d", len(args)))
+			continue
+		}
+		if expr, ok := zeroDefault(sf.Tag, sf.Type); ok && fv.IsZero() {
+			keys = append(keys, name)
+			values = append(values, expr)
+			continue
 		}
-		if valueKind == reflect.Slice {
-			sliceValue, _ := json.Marshal(value)
-			strValue = fmt.Sprintf("'%s'", string(sliceValue))
+		arg, cast, skip, err := bindValue(sf, fv)
+		if err != nil {
+			return nil, err
 		}
-		if valueKind == reflect.Pointer {
+		if skip {
 			continue
 		}
-		keys = append(keys, fmt.Sprintf("%s", name))
-		values = append(values, strValue)
+		args = append(args, arg)
+		keys = append(keys, name)
+		values = append(values, fmt.Sprintf("$%d%s", len(args), cast))
 	}
 	return &KV{
 		Key:   strings.Join(keys, ","),
 		Value: strings.Join(values, ","),
+		Args:  args,
+	}, nil
+}
+
+const (
+	zeroWrite = iota
+	zeroOmit
+	zeroNull
+)
+
+// zeroIntent reports how a zero field is written: skipped for
+// orm:"omitempty", so the column keeps its default or current value, or as
+// NULL for orm:"forceNull". Other fields are written as usual.
+func zeroIntent(tag reflect.StructTag, v reflect.Value) int {
+	switch {
+	case !v.IsZero():
+		return zeroWrite
+	case hasOrmOption(tag, "omitempty"):
+		return zeroOmit
+	case hasOrmOption(tag, "forceNull"):
+		return zeroNull
+	}
+	return zeroWrite
+}
+
+// zeroDefault reports whether a zero field of type t takes the column
+// default instead of its value: fields with a defaultExpr and time.Time
+// fields, unless tagged orm:"forceNull". expr is what Insert writes.
+func zeroDefault(tag reflect.StructTag, t reflect.Type) (expr string, ok bool) {
+	switch {
+	case hasOrmOption(tag, "forceNull"):
+		return "", false
+	case tag.Get("defaultExpr") != "":
+		return tag.Get("defaultExpr"), true
+	case t == reflect.TypeOf(time.Time{}):
+		return "DEFAULT", true
+	}
+	return "", false
+}
+
+// hasOrmOption reports whether the orm tag lists option.
+func hasOrmOption(tag reflect.StructTag, option string) bool {
+	for _, opt := range strings.Split(tag.Get("orm"), ",") {
+		if strings.TrimSpace(opt) == option {
+			return true
+		}
 	}
+	return false
 }
 
-var convertSlice2StringFuncMap = map[reflect.Kind]func(meta any) string{
-	reflect.String: func(meta any) string {
-		if v := meta.([]string); v != nil {
-			return `'` + strings.Join(v, "','") + `'`
+// bindValue converts a struct field into a statement argument. cast is
+// appended to the placeholder; skip reports fields that are not written.
+func bindValue(sf reflect.StructField, v reflect.Value) (arg any, cast string, skip bool, err error) {
+	if typ := sf.Tag.Get("composite"); typ != "" {
+		if text, ok := compositeText(v); ok {
+			return text, "::" + typ, false, nil
 		}
-		return ""
-	},
-	reflect.Int64: func(meta any) string {
-		if list := meta.([]int64); list != nil {
-			var l []string
-			for _, s := range list {
-				l = append(l, fmt.Sprintf("%v", s))
-			}
-			return strings.Join(l, ",")
-		}
-		return ""
-	},
-	reflect.Int: func(meta any) string {
-		if list := meta.([]int); list != nil {
-			var l []string
-			for _, s := range list {
-				l = append(l, fmt.Sprintf("%v", s))
-			}
-			return strings.Join(l, ",")
-		}
-		return ""
-	},
-	reflect.Float64: func(meta any) string {
-		if list := meta.([]float64); list != nil {
-			var l []string
-			for _, s := range list {
-				l = append(l, fmt.Sprintf("%v", s))
-			}
-			return strings.Join(l, ",")
+		return nil, "::" + typ, false, nil
+	}
+	if ser := sf.Tag.Get("serializer"); ser != "" {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return nil, "", false, nil
+		}
+		codec, ok := serializerOf(ser)
+		if !ok {
+			return nil, "", false, fmt.Errorf("orm: unknown serializer %q", ser)
+		}
+		data, err := codec.Marshal(v.Interface())
+		if err != nil {
+			return nil, "", false, err
+		}
+		return string(data), "", false, nil
+	}
+	if conv, ok := converterOf(v.Type()); ok {
+		arg, err := conv.encode(v)
+		return arg, "", false, err
+	}
+	if _, ok := v.Interface().(time.Time); ok {
+		return v.Interface(), "", false, nil
+	}
+	if _, ok := v.Interface().(driver.Valuer); ok {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return nil, "", false, nil
 		}
-		return ""
-	},
+		return v.Interface(), "", false, nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil, "", false, nil
+		}
+		return bindValue(sf, v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, "", false, nil
+		}
+		return v.Interface(), "", false, nil
+	case reflect.Slice, reflect.Map, reflect.Struct:
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return nil, "", false, err
+		}
+		return string(data), "", false, nil
+	}
+	return v.Interface(), "", false, nil
 }
 
+// parseSqlIn expands each "IN $N" (or "IN ($N)") whose argument is a slice
+// into one placeholder per element, appending the elements to the args and
+// renumbering the other placeholders. An empty slice becomes an empty
+// subquery, so IN matches nothing and NOT IN everything. Slices used
+// elsewhere, []byte and driver.Valuer arguments are passed on unchanged.
 func parseSqlIn(sqlStr string, args []any) (newSqlStr string, newArgs []any) {
-	var sliceArgs []string
-	for _, arg := range args {
-		if reflect.TypeOf(arg).Kind() == reflect.Slice {
-			if argValue := reflect.ValueOf(arg); argValue.Len() > 0 {
-				elemType := argValue.Index(0).Kind()
-				if find, ok := convertSlice2StringFuncMap[elemType]; ok {
-					sliceArgs = append(sliceArgs, find(argValue.Interface()))
-				}
+	if !strings.Contains(sqlStr, "$") {
+		return sqlStr, args
+	}
+	tokens := lexSQL(sqlStr)
+	// in maps the IN keyword tokens to the placeholder of their list and the
+	// token after the list.
+	type inList struct{ param, end int }
+	in := make(map[int]inList)
+	listed := make(map[int]bool)
+	expand := make(map[int]bool)
+	for i, tok := range tokens {
+		if tok.kind != tokWord || !strings.EqualFold(tok.text, "IN") {
+			continue
+		}
+		j := nextToken(tokens, i+1)
+		paren := j < len(tokens) && tokens[j].text == "("
+		if paren {
+			j = nextToken(tokens, j+1)
+		}
+		if j >= len(tokens) || tokens[j].kind != tokParam {
+			continue
+		}
+		end := j + 1
+		if paren {
+			if end = nextToken(tokens, end); end >= len(tokens) || tokens[end].text != ")" {
+				continue
 			}
+			end++
+		}
+		n, err := strconv.Atoi(tokens[j].text[1:])
+		if err != nil || n < 1 || n > len(args) || !expandable(args[n-1]) {
 			continue
 		}
-		newArgs = append(newArgs, arg)
+		in[i] = inList{param: j, end: end}
+		listed[j] = true
+		expand[n] = true
+	}
+	if len(expand) == 0 {
+		return sqlStr, args
+	}
+	// kept holds the arguments still referred to outside IN lists, which
+	// keep their own placeholder.
+	kept := make(map[int]bool)
+	for i, tok := range tokens {
+		if tok.kind == tokParam && !listed[i] {
+			if n, err := strconv.Atoi(tok.text[1:]); err == nil {
+				kept[n] = true
+			}
+		}
 	}
-	rep, _ := regexp.Compile(" (IN|in|In|iN) \\$[0-9]*")
-	sliceIdx := 0
-	sliceArgLen := len(sliceArgs)
-	newSqlStr = rep.ReplaceAllStringFunc(sqlStr, func(s string) string {
-		arg := ``
-		if sliceIdx < sliceArgLen {
-			arg = sliceArgs[sliceIdx]
+	renumber := make(map[int]int)
+	for n := 1; n <= len(args); n++ {
+		if !expand[n] || kept[n] {
+			newArgs = append(newArgs, args[n-1])
+			renumber[n] = len(newArgs)
+		}
+	}
+	lists := make(map[int]string)
+	var b strings.Builder
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if l, ok := in[i]; ok {
+			n, _ := strconv.Atoi(tokens[l.param].text[1:])
+			list, ok := lists[n]
+			if !ok {
+				v := reflect.ValueOf(args[n-1])
+				markers := make([]string, v.Len())
+				for k := range markers {
+					newArgs = append(newArgs, v.Index(k).Interface())
+					markers[k] = "$" + strconv.Itoa(len(newArgs))
+				}
+				list = "(" + strings.Join(markers, ", ") + ")"
+				if len(markers) == 0 {
+					list = "(SELECT NULL WHERE FALSE)"
+				}
+				lists[n] = list
+			}
+			b.WriteString(tok.text + " " + list)
+			i = l.end - 1
+			continue
 		}
-		sliceIdx++
-		if arg == `` {
-			return ` IN `
+		if tok.kind == tokParam {
+			if n, err := strconv.Atoi(tok.text[1:]); err == nil {
+				if m, ok := renumber[n]; ok {
+					b.WriteString("$" + strconv.Itoa(m))
+					continue
+				}
+			}
 		}
-		return ` IN (` + arg + `)`
-	})
-	return
+		b.WriteString(tok.text)
+	}
+	return b.String(), newArgs
 }
 
-func generateDelete(sqlStr string, dest any) (newSqlStr string) {
-	parse := regexp.MustCompile(`(?i)DELETE FROM (.*?) `)
-	parseArr := parse.FindAllStringSubmatch(sqlStr, -1)
-	if parseArr != nil {
+// nextToken returns the index of the first non-space token from i on.
+func nextToken(tokens []token, i int) int {
+	for i < len(tokens) && (tokens[i].kind == tokSpace || tokens[i].kind == tokComment) {
+		i++
+	}
+	return i
+}
+
+// expandable reports whether arg is a slice parseSqlIn spreads over an IN
+// list.
+func expandable(arg any) bool {
+	if _, ok := arg.(driver.Valuer); ok {
+		return false
+	}
+	v := reflect.ValueOf(arg)
+	return v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8
+}
+
+func generateDelete(tableName, sqlStr string) (newSqlStr string) {
+	if isDeleteStatement(sqlStr) {
 		return sqlStr
 	}
-	tableName := getTableName(dest)
 	return fmt.Sprintf("DELETE FROM %s WHERE %s", tableName, sqlStr)
 }
-func generateUpdate(sqlStr string, dest any) (newSqlStr string) {
+
+var deleteStatement = regexp.MustCompile(`(?i)DELETE FROM (.*?) `)
+
+// isDeleteStatement reports whether Delete was given a whole statement
+// rather than a where clause.
+func isDeleteStatement(sqlStr string) bool {
+	return deleteStatement.MatchString(sqlStr)
+}
+
+// generateUpdate builds the UPDATE for dest. The where clause keeps its own
+// $1..$n placeholders (n = whereArgs); SET values are numbered after them and
+// returned as args to append. It returns "" when opts leave nothing to set.
+func generateUpdate(h *handle, tableName, sqlStr string, whereArgs int, dest any, opts *queryOptions) (newSqlStr string, args []any, err error) {
 	parse := regexp.MustCompile(`(?i)DELETE (.*?) `)
 	parseArr := parse.FindAllStringSubmatch(sqlStr, -1)
 	if parseArr != nil {
-		return sqlStr
+		return sqlStr, nil, nil
 	}
-	tableName := getTableName(dest)
 	valueOf := reflect.ValueOf(dest)
 	typeOf := reflect.TypeOf(dest)
+	m := modelOf(h, typeOf)
 	var sets []string
-	for curField := 0; curField < typeOf.NumField(); curField++ {
-		fieldName := toSnake(typeOf.Field(curField).Name)
-		jsonName := typeOf.Field(curField).Tag.Get("json")
-		if jsonName != "" {
-			fieldName = jsonName
-		}
-		isPrimary := fieldName == "id" || typeOf.Field(curField).Tag.Get("pri") != ""
-		value := valueOf.Field(curField)
-		if isPrimary {
-			if sqlStr == "" {
-				sqlStr = fmt.Sprintf(`%s = %v`, fieldName, value)
-			}
+	for _, sf := range columnFields(typeOf) {
+		fieldName := columnOf(h, sf)
+		value := valueOf.FieldByIndex(sf.Index)
+		if f, _ := m.field(fieldName); f.Primary {
 			continue
 		}
-		var valueStr string
-		if value.Kind() == reflect.String || value.Kind() == reflect.Struct || value.Kind() == reflect.Interface {
-			valueStr = fmt.Sprintf("'%v'", value)
-		} else {
-			valueStr = fmt.Sprintf("%v", value)
+		if sf.Tag.Get("generated") != "" || (opts.columns != nil && !opts.columns[fieldName]) {
+			continue
 		}
-		if value.Kind() == reflect.Struct {
-			if t, ok := value.Interface().(time.Time); ok {
-				valueStr = t.Format(`'2006-01-02 15:04:05'`)
-			}
+		switch zeroIntent(sf.Tag, value) {
+		case zeroOmit:
+			continue
+		case zeroNull:
+			args = append(args, nil)
+			sets = append(sets, fmt.Sprintf("%s=$%d", fieldName, whereArgs+len(args)))
+			continue
+		}
+		if opts.skipZero && value.IsZero() {
+			continue
 		}
-		if value.Kind() == reflect.Slice {
-			sliceValue, _ := json.Marshal(value.Interface())
-			valueStr = fmt.Sprintf("'%s'", string(sliceValue))
+		arg, cast, skip, err := bindValue(sf, value)
+		if err != nil {
+			return "", nil, err
 		}
-		if value.Kind() == reflect.Pointer {
+		if skip {
 			continue
 		}
-		sets = append(sets, fmt.Sprintf("%s=%s", fieldName, valueStr))
+		args = append(args, arg)
+		sets = append(sets, fmt.Sprintf("%s=$%d%s", fieldName, whereArgs+len(args), cast))
+	}
+	if len(sets) == 0 {
+		return "", nil, nil
+	}
+	if sqlStr == "" {
+		var conds []string
+		for _, f := range m.primaryKey() {
+			args = append(args, valueOf.FieldByIndex(f.Index).Interface())
+			conds = append(conds, fmt.Sprintf(`%s = $%d`, f.Column, whereArgs+len(args)))
+		}
+		if len(conds) == 0 {
+			return "", nil, ErrNoPrimaryKey
+		}
+		sqlStr = strings.Join(conds, " AND ")
 	}
 	newSqlStr = fmt.Sprintf("UPDATE %s SET %s WHERE %s", tableName, strings.Join(sets, ","), sqlStr)
 	return
 }
 
 func outputSql(s string, args []any) {
-	for i, arg := range args {
-		v := fmt.Sprintf("%v", arg)
-		if reflect.TypeOf(arg).Kind() == reflect.String || reflect.TypeOf(arg).Kind() == reflect.Struct {
-			v = fmt.Sprintf("'%v'", arg)
-		}
-		s = strings.Replace(s, fmt.Sprintf("$%v", i+1), v, i+1)
+	recordFingerprint(s)
+	log.Printf("[ORM INFO]\t %s \n", renderSql(s, args))
+}
+
+// outputTimed logs s like outputSql with its execution time since start,
+// as a warning when it exceeds the slow query threshold.
+func outputTimed(s string, args []any, start time.Time) {
+	recordFingerprint(s)
+	elapsed := time.Since(start)
+	if threshold := SlowQueryThreshold(); threshold > 0 && elapsed >= threshold {
+		log.Printf("[ORM WARN]\t %s -- slow query: %v exceeds %v\n", renderSql(s, args), elapsed, threshold)
+		return
 	}
-	log.Printf("[ORM INFO]\t %s \n", s)
+	log.Printf("[ORM INFO]\t %s -- %v\n", renderSql(s, args), elapsed)
 }
 
-var savePriFieldMap = map[reflect.Kind]func(value reflect.Value, filedIdx int, lastId int64){
-	reflect.Int: func(value reflect.Value, filedIdx int, lastId int64) {
-		value.Elem().Field(filedIdx).Set(reflect.ValueOf(int(lastId)))
-	},
-	reflect.Int64: func(value reflect.Value, filedIdx int, lastId int64) {
-		value.Elem().Field(filedIdx).Set(reflect.ValueOf(lastId))
-	},
+// renderSql formats s with its arguments inlined for the query log.
+func renderSql(s string, args []any) string {
+	var b strings.Builder
+	for _, tok := range lexSQL(FormatSQL(s)) {
+		n, err := strconv.Atoi(strings.TrimPrefix(tok.text, "$"))
+		if tok.kind != tokParam || err != nil || n < 1 || n > len(args) {
+			b.WriteString(tok.text)
+			continue
+		}
+		b.WriteString(logArg(args[n-1]))
+	}
+	return b.String()
 }
 
-func savePrimaryKey(dest any, lastId int64) {
-	typeOf := reflect.TypeOf(dest)
-	if typeOf.Kind() != reflect.Pointer {
-		return
+// logArg renders a bound argument for the query log.
+func logArg(arg any) string {
+	if arg == nil {
+		return "NULL"
 	}
-	typeOf = typeOf.Elem()
-	valueOf := reflect.ValueOf(dest).Elem()
-	if typeOf.Kind() != reflect.Struct {
-		return
+	if valuer, ok := arg.(driver.Valuer); ok {
+		if v, err := valuer.Value(); err == nil {
+			return logArg(v)
+		}
 	}
-	for cur := 0; cur < typeOf.NumField(); cur++ {
-		name := toSnake(typeOf.Field(cur).Name)
-		nameTag := typeOf.Field(cur).Tag.Get("json")
-		isPri := typeOf.Field(cur).Tag.Get("pri") != ""
-		if name == "id" || nameTag == "id" || isPri {
-			fieldKind := valueOf.Field(cur).Kind()
-			convert, ok := savePriFieldMap[fieldKind]
-			if ok {
-				convert(reflect.ValueOf(dest), cur, lastId)
-				return
-			}
+	switch reflect.TypeOf(arg).Kind() {
+	case reflect.String, reflect.Struct:
+		return fmt.Sprintf("'%v'", arg)
+	}
+	return fmt.Sprintf("%v", arg)
+}
+
+func savePrimaryKey(h *handle, dest any, lastId int64) {
+	valueOf := reflect.ValueOf(dest).Elem()
+	for _, f := range returnedKeys(h, dest) {
+		switch fv := valueOf.FieldByIndex(f.Index); fv.Kind() {
+		case reflect.Int, reflect.Int64:
+			fv.SetInt(lastId)
+			return
 		}
 	}
 }
 
-func toSnake(name string) string {
-	var convert []byte
-	for i, asc := range name {
-		if asc >= 65 && asc <= 90 {
-			asc += 32
-			if i > 0 {
-				convert = append(convert, 95)
-			}
+// returnedKeys returns the primary key fields of the struct pointed to by
+// dest that are left for the database to fill: zero and not generated by
+// the client.
+func returnedKeys(h *handle, dest any) []*field {
+	valueOf := reflect.ValueOf(dest).Elem()
+	if valueOf.Kind() != reflect.Struct {
+		return nil
+	}
+	var keys []*field
+	for _, f := range modelOf(h, valueOf.Type()).primaryKey() {
+		if valueOf.FieldByIndex(f.Index).IsZero() {
+			keys = append(keys, f)
 		}
-		convert = append(convert, uint8(asc))
 	}
-	return string(convert)
+	return keys
 }
@@ -0,0 +1,338 @@
+// Package pgdump wraps the pg_dump and pg_restore binaries for provisioning
+// databases: copying a schema, seeding selected tables or restoring a
+// snapshot before migrations run.
+package pgdump
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Format is the pg_dump archive format.
+type Format string
+
+const (
+	Plain     Format = "p"
+	Custom    Format = "c"
+	Directory Format = "d"
+	Tar       Format = "t"
+)
+
+// Progress reports one object being processed by pg_dump or pg_restore.
+type Progress struct {
+	// Action is the verb reported by the tool, e.g. "dumping contents of".
+	Action string
+	// Object is the object kind, e.g. "table".
+	Object string
+	// Name is the object name as printed, usually schema qualified.
+	Name string
+}
+
+// Options configures a dump or restore.
+type Options struct {
+	// DSN is the connection string (URL or key=value) of the database.
+	DSN string
+	// File is the archive path; empty streams through Output/Input.
+	File string
+	// Format defaults to Custom.
+	Format Format
+	// SchemaOnly and DataOnly restrict what is dumped or restored.
+	SchemaOnly bool
+	DataOnly   bool
+	// Schemas, Tables and ExcludeTables filter objects; patterns follow the
+	// pg_dump -n / -t / -T syntax.
+	Schemas       []string
+	Tables        []string
+	ExcludeTables []string
+	// Jobs runs the tool in parallel (directory format dumps, restores).
+	Jobs int
+	// Clean drops objects before recreating them (restore only).
+	Clean bool
+	// NoOwner skips ownership commands, handy across environments.
+	NoOwner bool
+	// Binary overrides the executable path.
+	Binary string
+	// Output receives the dump when File is empty; Input feeds pg_restore.
+	Output io.Writer
+	Input  io.Reader
+	// OnProgress is called for every object reported in verbose mode.
+	OnProgress func(Progress)
+}
+
+// Error is returned when the tool exits unsuccessfully.
+type Error struct {
+	Tool   string
+	Code   int
+	Stderr string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s exited with %d: %s", e.Tool, e.Code, e.Stderr)
+}
+
+// Dump runs pg_dump.
+func Dump(ctx context.Context, opts Options) error {
+	args := []string{"--format", string(formatOf(opts))}
+	if opts.File != "" {
+		args = append(args, "--file", opts.File)
+	}
+	args = append(args, opts.filters()...)
+	cmd, err := command(ctx, opts, "pg_dump", args)
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = opts.Output
+	return run(cmd, "pg_dump", opts.OnProgress)
+}
+
+// Restore runs pg_restore. A Plain archive cannot be restored this way; feed
+// it to psql instead.
+func Restore(ctx context.Context, opts Options) error {
+	if opts.Format == Plain {
+		return errors.New("pgdump: pg_restore does not read plain format archives")
+	}
+	var args []string
+	if opts.Format != "" {
+		args = append(args, "--format", string(opts.Format))
+	}
+	if opts.Clean {
+		args = append(args, "--clean", "--if-exists")
+	}
+	args = append(args, opts.filters()...)
+	if opts.File != "" {
+		args = append(args, opts.File)
+	}
+	cmd, err := command(ctx, opts, "pg_restore", args)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = opts.Input
+	return run(cmd, "pg_restore", opts.OnProgress)
+}
+
+// Copy dumps src and restores it into dst through a pipe, without an
+// intermediate file. Filters and flags are taken from src; dst only needs
+// DSN (and optionally Binary, Clean, Jobs and OnProgress).
+func Copy(ctx context.Context, src, dst Options) error {
+	r, w := io.Pipe()
+	src.Format, src.File, src.Output = Custom, "", w
+	dst.Format, dst.File, dst.Input = Custom, "", r
+	dst.NoOwner = dst.NoOwner || src.NoOwner
+	dst.Jobs = 0 // parallel restore needs a seekable file
+	done := make(chan error, 1)
+	go func() {
+		err := Dump(ctx, src)
+		done <- err // before closing, so Restore never ends ahead of it
+		w.CloseWithError(err)
+	}()
+	err := Restore(ctx, dst)
+	// A dump that ended before the restore fed it its error or truncated
+	// input; one still running only fails because the restore stopped
+	// reading, so the restore error is the cause.
+	select {
+	case dumpErr := <-done:
+		if dumpErr != nil {
+			return dumpErr
+		}
+		return err
+	default:
+	}
+	r.Close()
+	dumpErr := <-done
+	if err != nil {
+		return err
+	}
+	return dumpErr
+}
+
+// CopySchema copies the schema (no data) of src into dst.
+func CopySchema(ctx context.Context, src, dst Options) error {
+	src.SchemaOnly, src.DataOnly = true, false
+	return Copy(ctx, src, dst)
+}
+
+func (o Options) filters() []string {
+	var args []string
+	if o.SchemaOnly {
+		args = append(args, "--schema-only")
+	}
+	if o.DataOnly {
+		args = append(args, "--data-only")
+	}
+	if o.NoOwner {
+		args = append(args, "--no-owner")
+	}
+	if o.Jobs > 1 {
+		args = append(args, "--jobs", strconv.Itoa(o.Jobs))
+	}
+	for _, s := range o.Schemas {
+		args = append(args, "--schema", s)
+	}
+	for _, t := range o.Tables {
+		args = append(args, "--table", t)
+	}
+	for _, t := range o.ExcludeTables {
+		args = append(args, "--exclude-table", t)
+	}
+	if o.OnProgress != nil {
+		args = append(args, "--verbose")
+	}
+	return args
+}
+
+func formatOf(o Options) Format {
+	if o.Format == "" {
+		return Custom
+	}
+	return o.Format
+}
+
+// command builds the tool invocation. The password is moved from the DSN to
+// PGPASSWORD so it does not show up in the process list.
+func command(ctx context.Context, o Options, name string, args []string) (*exec.Cmd, error) {
+	dsn, password, err := splitPassword(o.DSN)
+	if err != nil {
+		return nil, err
+	}
+	args = append([]string{"--dbname", dsn}, args...)
+	cmd := exec.CommandContext(ctx, binary(o, name), args...)
+	if password != "" {
+		cmd.Env = append(os.Environ(), "PGPASSWORD="+password)
+	}
+	return cmd, nil
+}
+
+// splitPassword removes the password from a URL or key=value DSN and returns
+// it separately.
+func splitPassword(dsn string) (string, string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", "", fmt.Errorf("pgdump: parse dsn: %w", err)
+		}
+		password := ""
+		if u.User != nil {
+			password, _ = u.User.Password()
+			u.User = url.User(u.User.Username())
+		}
+		q := u.Query()
+		if p := q.Get("password"); p != "" {
+			password = p
+			q.Del("password")
+			u.RawQuery = q.Encode()
+		}
+		return u.String(), password, nil
+	}
+	var kept []string
+	password := ""
+	for rest := strings.TrimSpace(dsn); rest != ""; rest = strings.TrimSpace(rest) {
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return "", "", fmt.Errorf("pgdump: parse dsn: missing \"=\" after %q", rest)
+		}
+		key := strings.TrimSpace(rest[:eq])
+		rest = strings.TrimLeft(rest[eq+1:], " ")
+		raw, value, n := dsnValue(rest)
+		rest = rest[n:]
+		if key == "password" {
+			password = value
+			continue
+		}
+		kept = append(kept, key+"="+raw)
+	}
+	return strings.Join(kept, " "), password, nil
+}
+
+// dsnValue reads one key=value value, quoted or bare, returning it as written,
+// unescaped, and the number of bytes consumed.
+func dsnValue(s string) (string, string, int) {
+	var b strings.Builder
+	if strings.HasPrefix(s, "'") {
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				if i+1 < len(s) {
+					i++
+					b.WriteByte(s[i])
+				}
+			case '\'':
+				return s[:i+1], b.String(), i + 1
+			default:
+				b.WriteByte(s[i])
+			}
+		}
+		return s, b.String(), len(s)
+	}
+	i := 0
+	for ; i < len(s) && s[i] != ' '; i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return s[:i], b.String(), i
+}
+
+func binary(o Options, name string) string {
+	if o.Binary != "" {
+		return o.Binary
+	}
+	return name
+}
+
+// progressLine matches verbose lines such as
+// `pg_dump: dumping contents of table "public.users"` or
+// `pg_restore: processing data for table "public.users"`.
+var progressLine = regexp.MustCompile(`^pg_(?:dump|restore): (dumping contents of|processing data for|creating) ([A-Z]+|table|sequence|index) "?([^"]*)"?`)
+
+// parseProgress extracts a Progress from one verbose stderr line.
+func parseProgress(line string) (Progress, bool) {
+	m := progressLine.FindStringSubmatch(line)
+	if m == nil {
+		return Progress{}, false
+	}
+	return Progress{Action: m[1], Object: m[2], Name: m[3]}, true
+}
+
+func run(cmd *exec.Cmd, tool string, onProgress func(Progress)) error {
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	var tail bytes.Buffer
+	sc := bufio.NewScanner(stderr)
+	for sc.Scan() {
+		line := sc.Text()
+		if p, ok := parseProgress(line); ok {
+			if onProgress != nil {
+				onProgress(p)
+			}
+			continue
+		}
+		if tail.Len() < 4096 {
+			tail.WriteString(line)
+			tail.WriteByte('\n')
+		}
+	}
+	if err = cmd.Wait(); err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			return &Error{Tool: tool, Code: exit.ExitCode(), Stderr: string(bytes.TrimSpace(tail.Bytes()))}
+		}
+		return err
+	}
+	return nil
+}
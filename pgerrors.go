@@ -0,0 +1,44 @@
+package orm
+
+// SQLState returns the Postgres SQLSTATE code carried by err, from lib/pq,
+// pgx or any error with a SQLState method, or "" when there is none.
+func SQLState(err error) string {
+	v, _ := violationOf(err)
+	return v.code
+}
+
+// ConstraintName returns the name of the constraint err violated, or "".
+func ConstraintName(err error) string {
+	v, _ := violationOf(err)
+	return v.constraint
+}
+
+// IsUniqueViolation reports whether err is a unique constraint violation.
+func IsUniqueViolation(err error) bool {
+	return SQLState(err) == "23505"
+}
+
+// IsForeignKeyViolation reports whether err is a foreign key violation.
+func IsForeignKeyViolation(err error) bool {
+	return SQLState(err) == "23503"
+}
+
+// IsNotNullViolation reports whether err is a not-null violation.
+func IsNotNullViolation(err error) bool {
+	return SQLState(err) == "23502"
+}
+
+// IsCheckViolation reports whether err is a check constraint violation.
+func IsCheckViolation(err error) bool {
+	return SQLState(err) == "23514"
+}
+
+// IsSerializationFailure reports whether err is a serialization failure or
+// a deadlock, after which the transaction can be retried.
+func IsSerializationFailure(err error) bool {
+	switch SQLState(err) {
+	case "40001", "40P01":
+		return true
+	}
+	return false
+}
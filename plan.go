@@ -0,0 +1,170 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var ErrPlan = errors.New("orm: plan")
+
+// Plan is a sequence of dependent writes run in one transaction, where later
+// steps take keys produced by earlier ones:
+//
+//	plan := orm.NewPlan()
+//	user := orm.PlanInsert(plan, &User{Name: "ada"})
+//	orm.PlanInsert(plan, &Post{Title: "hello"}, orm.Ref("UserID", user, "ID"))
+//	orm.PlanExec(plan, "UPDATE stats SET last_user = $1", orm.StepValue(user, "ID"))
+//	err := plan.Run(ctx, db)
+//
+// The rows passed in are filled with their generated keys as the steps run;
+// after a failed Run they may hold keys of rolled back rows.
+type Plan struct {
+	steps []*Step
+}
+
+// Step is one write of a Plan.
+type Step struct {
+	plan  *Plan
+	index int
+	// row is the step's struct, invalid for PlanExec steps.
+	row  reflect.Value
+	refs []PlanRef
+	run  func(ctx context.Context, tx Executor) error
+}
+
+// PlanRef copies a field of an earlier step's row into a field of this
+// step's row right before it runs.
+type PlanRef struct {
+	to   string
+	from *Step
+	of   string
+}
+
+// Ref sets field to of the step's row from field of of step from.
+func Ref(to string, from *Step, of string) PlanRef {
+	return PlanRef{to: to, from: from, of: of}
+}
+
+type stepValue struct {
+	step  *Step
+	field string
+}
+
+// StepValue stands for field of step's row in the arguments of PlanExec,
+// read when the statement runs.
+func StepValue(step *Step, field string) any {
+	return stepValue{step: step, field: field}
+}
+
+// NewPlan returns an empty plan.
+func NewPlan() *Plan {
+	return &Plan{}
+}
+
+func (p *Plan) add(s *Step) *Step {
+	s.plan, s.index = p, len(p.steps)+1
+	p.steps = append(p.steps, s)
+	return s
+}
+
+// PlanInsert adds an Insert of row, whose generated keys are written back
+// into it.
+func PlanInsert[T any](p *Plan, row *T, refs ...PlanRef) *Step {
+	return p.add(&Step{row: reflect.ValueOf(row).Elem(), refs: refs, run: func(ctx context.Context, tx Executor) error {
+		rows, err := Insert(ctx, tx, []T{*row})
+		if err == nil && len(rows) == 1 {
+			*row = rows[0]
+		}
+		return err
+	}})
+}
+
+// PlanUpdate adds an Update of row matched by its primary key.
+func PlanUpdate[T any](p *Plan, row *T, refs ...PlanRef) *Step {
+	return p.add(&Step{row: reflect.ValueOf(row).Elem(), refs: refs, run: func(ctx context.Context, tx Executor) error {
+		_, err := Update(ctx, tx, []T{*row}, "")
+		return err
+	}})
+}
+
+// PlanExec adds a statement; StepValue arguments are resolved when it runs.
+func PlanExec(p *Plan, sqlStr string, args ...any) *Step {
+	s := &Step{}
+	s.run = func(ctx context.Context, tx Executor) error {
+		resolved := make([]any, len(args))
+		for i, arg := range args {
+			v, ok := arg.(stepValue)
+			if !ok {
+				resolved[i] = arg
+				continue
+			}
+			fv, err := s.fieldOf(v.step, v.field)
+			if err != nil {
+				return err
+			}
+			resolved[i] = fv.Interface()
+		}
+		return Exec(ctx, tx, sqlStr, resolved...)
+	}
+	return p.add(s)
+}
+
+// fieldOf returns field of the row of an earlier step of s's plan.
+func (s *Step) fieldOf(from *Step, field string) (reflect.Value, error) {
+	if from == nil || from.plan != s.plan || from.index >= s.index {
+		return reflect.Value{}, fmt.Errorf("%w: step %d refers to a step that does not run before it", ErrPlan, s.index)
+	}
+	if !from.row.IsValid() {
+		return reflect.Value{}, fmt.Errorf("%w: step %d refers to step %d, which has no row", ErrPlan, s.index, from.index)
+	}
+	fv := from.row.FieldByName(field)
+	if !fv.IsValid() {
+		return reflect.Value{}, fmt.Errorf("%w: step %d: %s has no field %s", ErrPlan, s.index, from.row.Type().Name(), field)
+	}
+	return fv, nil
+}
+
+// resolve applies the refs of s to its row.
+func (s *Step) resolve() error {
+	for _, ref := range s.refs {
+		src, err := s.fieldOf(ref.from, ref.of)
+		if err != nil {
+			return err
+		}
+		dst := s.row.FieldByName(ref.to)
+		if !dst.IsValid() || !dst.CanSet() {
+			return fmt.Errorf("%w: step %d: %s has no field %s", ErrPlan, s.index, s.row.Type().Name(), ref.to)
+		}
+		switch {
+		case src.Type().AssignableTo(dst.Type()):
+			dst.Set(src)
+		case src.Type().ConvertibleTo(dst.Type()):
+			dst.Set(src.Convert(dst.Type()))
+		case dst.Kind() == reflect.Pointer && src.Type().AssignableTo(dst.Type().Elem()):
+			p := reflect.New(src.Type())
+			p.Elem().Set(src)
+			dst.Set(p)
+		default:
+			return fmt.Errorf("%w: step %d: cannot set %s (%s) from %s (%s)", ErrPlan, s.index, ref.to, dst.Type(), ref.of, src.Type())
+		}
+	}
+	return nil
+}
+
+// Run executes the steps in order in one transaction on db; any failure
+// rolls back every step.
+func (p *Plan) Run(ctx context.Context, db Executor) error {
+	return Transaction(ctx, db, func(tx Executor) error {
+		for _, s := range p.steps {
+			if err := s.resolve(); err != nil {
+				return err
+			}
+			if err := s.run(ctx, tx); err != nil {
+				return fmt.Errorf("orm: plan step %d: %w", s.index, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,132 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// PoolRecommendation summarises pool pressure over the sampled window.
+type PoolRecommendation struct {
+	MaxOpen          int
+	SuggestedMaxOpen int
+	PeakInUse        int
+	Saturation       float64 // share of samples with every connection in use
+	Waits            int64
+	AvgWait          time.Duration
+	IdleClosed       int64
+	Hints            []string
+}
+
+// PoolAdvisor collects sql.DBStats samples and suggests a MaxOpenConns value.
+type PoolAdvisor struct {
+	DB *sql.DB
+	// Window is the number of samples kept; it defaults to 60.
+	Window int
+
+	mu      sync.Mutex
+	samples []sql.DBStats
+}
+
+// NewPoolAdvisor returns an advisor keeping the last window samples of db.
+func NewPoolAdvisor(db *sql.DB, window int) *PoolAdvisor {
+	return &PoolAdvisor{DB: db, Window: window}
+}
+
+// window returns Window, or its default when unset.
+func (p *PoolAdvisor) window() int {
+	if p.Window <= 0 {
+		return 60
+	}
+	return p.Window
+}
+
+// Sample records the current pool statistics.
+func (p *PoolAdvisor) Sample() {
+	window := p.window()
+	stats := p.DB.Stats()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.samples = append(p.samples, stats)
+	if len(p.samples) > window {
+		p.samples = p.samples[len(p.samples)-window:]
+	}
+}
+
+// Run samples every interval until ctx is done and logs the hints of every
+// full window.
+func (p *PoolAdvisor) Run(ctx context.Context, interval time.Duration) {
+	window := p.window()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	n := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		p.Sample()
+		if n++; n%window != 0 {
+			continue
+		}
+		for _, hint := range p.Recommend().Hints {
+			log.Printf("[ORM INFO]\t pool: %s\n", hint)
+		}
+	}
+}
+
+// Recommend analyses the samples collected so far.
+func (p *PoolAdvisor) Recommend() PoolRecommendation {
+	p.mu.Lock()
+	samples := append([]sql.DBStats(nil), p.samples...)
+	p.mu.Unlock()
+	var rec PoolRecommendation
+	if len(samples) == 0 {
+		return rec
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	rec.MaxOpen = last.MaxOpenConnections
+	rec.SuggestedMaxOpen = rec.MaxOpen
+	rec.Waits = last.WaitCount - first.WaitCount
+	rec.IdleClosed = last.MaxIdleClosed - first.MaxIdleClosed
+	if rec.Waits > 0 {
+		rec.AvgWait = (last.WaitDuration - first.WaitDuration) / time.Duration(rec.Waits)
+	}
+	saturated := 0
+	for _, s := range samples {
+		if s.InUse > rec.PeakInUse {
+			rec.PeakInUse = s.InUse
+		}
+		if s.MaxOpenConnections > 0 && s.InUse >= s.MaxOpenConnections {
+			saturated++
+		}
+	}
+	rec.Saturation = float64(saturated) / float64(len(samples))
+	switch {
+	case rec.MaxOpen == 0:
+		rec.Hints = append(rec.Hints, fmt.Sprintf("MaxOpenConns is unlimited; peak usage was %d, consider capping it near %d", rec.PeakInUse, rec.PeakInUse*2+1))
+		rec.SuggestedMaxOpen = rec.PeakInUse*2 + 1
+	case rec.Saturation >= 0.25 && rec.AvgWait > time.Millisecond:
+		grow := rec.MaxOpen / 2
+		if grow < 2 {
+			grow = 2
+		}
+		rec.SuggestedMaxOpen = rec.MaxOpen + grow
+		rec.Hints = append(rec.Hints, fmt.Sprintf("pool saturated in %.0f%% of samples with %s average wait, raise MaxOpenConns to %d", rec.Saturation*100, rec.AvgWait, rec.SuggestedMaxOpen))
+	case rec.Waits == 0 && rec.PeakInUse*2 < rec.MaxOpen && rec.MaxOpen > 4:
+		suggested := rec.PeakInUse * 2
+		if suggested < 4 {
+			suggested = 4
+		}
+		rec.SuggestedMaxOpen = suggested
+		rec.Hints = append(rec.Hints, fmt.Sprintf("peak usage %d of %d connections, MaxOpenConns could be lowered to %d", rec.PeakInUse, rec.MaxOpen, suggested))
+	}
+	if rec.IdleClosed > int64(len(samples)) {
+		rec.Hints = append(rec.Hints, fmt.Sprintf("%d connections closed by MaxIdleConns, consider raising it", rec.IdleClosed))
+	}
+	return rec
+}
@@ -0,0 +1,149 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+var ErrUnknownQuery = errors.New("orm: unknown named query")
+
+// NamedQuery is a statement loaded by LoadQueries.
+type NamedQuery struct {
+	Name string
+	SQL  string
+	// File and Line locate the name marker, for error messages.
+	File string
+	Line int
+}
+
+// Queries is a set of named statements kept in .sql files, typically an
+// embed.FS:
+//
+//	-- name: GetUserByEmail
+//	SELECT * FROM users WHERE email = $1;
+//
+//	//go:embed sql/*.sql
+//	var sqlFiles embed.FS
+//	var queries = orm.MustLoadQueries(sqlFiles)
+//
+//	user, err := orm.QueryNamed[User](ctx, db, queries, "GetUserByEmail", email)
+type Queries struct {
+	byName map[string]NamedQuery
+}
+
+// LoadQueries reads every .sql file of fsys. Each statement starts with a
+// "-- name: X" line and runs to the next one; names must be unique across
+// files and a trailing semicolon is dropped.
+func LoadQueries(fsys fs.FS) (*Queries, error) {
+	q := &Queries{byName: make(map[string]NamedQuery)}
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != ".sql" {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		return q.parse(p, string(data))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// MustLoadQueries is LoadQueries panicking on error, for package variables.
+func MustLoadQueries(fsys fs.FS) *Queries {
+	q, err := LoadQueries(fsys)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+func (q *Queries) parse(file, text string) error {
+	var cur *NamedQuery
+	var body []string
+	flush := func() error {
+		if cur == nil {
+			return nil
+		}
+		cur.SQL = strings.TrimSuffix(strings.TrimSpace(strings.Join(body, "\n")), ";")
+		if cur.SQL == "" {
+			return fmt.Errorf("orm: %s:%d: query %s is empty", file, cur.Line, cur.Name)
+		}
+		if prev, ok := q.byName[cur.Name]; ok {
+			return fmt.Errorf("orm: %s:%d: query %s already defined at %s:%d", file, cur.Line, cur.Name, prev.File, prev.Line)
+		}
+		q.byName[cur.Name] = *cur
+		return nil
+	}
+	for i, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if rest := strings.TrimPrefix(trimmed, "--"); rest != trimmed {
+			if rest = strings.TrimSpace(rest); strings.HasPrefix(rest, "name:") {
+				if err := flush(); err != nil {
+					return err
+				}
+				name := strings.TrimSpace(strings.TrimPrefix(rest, "name:"))
+				cur, body = &NamedQuery{Name: name, File: file, Line: i + 1}, nil
+				continue
+			}
+		}
+		if cur == nil {
+			if trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+				return fmt.Errorf("orm: %s:%d: statement without a -- name: line", file, i+1)
+			}
+			continue
+		}
+		body = append(body, line)
+	}
+	return flush()
+}
+
+// Get returns the named query.
+func (q *Queries) Get(name string) (NamedQuery, bool) {
+	nq, ok := q.byName[name]
+	return nq, ok
+}
+
+// Names returns the query names in sorted order.
+func (q *Queries) Names() []string {
+	names := make([]string, 0, len(q.byName))
+	for name := range q.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (q *Queries) sql(name string) (string, error) {
+	nq, ok := q.byName[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownQuery, name)
+	}
+	return nq.SQL, nil
+}
+
+// QueryNamed runs the named query and scans the result into T like Query.
+func QueryNamed[T any](ctx context.Context, db Executor, q *Queries, name string, args ...any) (*T, error) {
+	sqlStr, err := q.sql(name)
+	if err != nil {
+		return nil, err
+	}
+	return Query[T](ctx, db, sqlStr, args...)
+}
+
+// Exec runs the named statement.
+func (q *Queries) Exec(ctx context.Context, db Executor, name string, args ...any) error {
+	sqlStr, err := q.sql(name)
+	if err != nil {
+		return err
+	}
+	return Exec(ctx, db, sqlStr, args...)
+}
@@ -0,0 +1,122 @@
+package orm
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var ErrRange = errors.New("range: malformed value")
+
+// Range maps Postgres range columns (int4range, int8range, numrange,
+// tsrange, tstzrange, daterange). Bounds are inclusive when LowerInc/UpperInc
+// are set and unbounded when LowerInf/UpperInf are set.
+type Range[T any] struct {
+	Lower    T
+	Upper    T
+	LowerInc bool
+	UpperInc bool
+	LowerInf bool
+	UpperInf bool
+	Empty    bool
+}
+
+// NewRange returns the half-open range [lower,upper), Postgres' canonical
+// form.
+func NewRange[T any](lower, upper T) Range[T] {
+	return Range[T]{Lower: lower, Upper: upper, LowerInc: true}
+}
+
+func (r Range[T]) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+func (r Range[T]) String() string {
+	if r.Empty {
+		return "empty"
+	}
+	var b strings.Builder
+	if r.LowerInc && !r.LowerInf {
+		b.WriteByte('[')
+	} else {
+		b.WriteByte('(')
+	}
+	if !r.LowerInf {
+		b.WriteString(rangeBound(r.Lower))
+	}
+	b.WriteByte(',')
+	if !r.UpperInf {
+		b.WriteString(rangeBound(r.Upper))
+	}
+	if r.UpperInc && !r.UpperInf {
+		b.WriteByte(']')
+	} else {
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+func (r *Range[T]) Scan(src any) error {
+	var text string
+	switch v := src.(type) {
+	case nil:
+		*r = Range[T]{}
+		return nil
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	default:
+		return fmt.Errorf("%w: unexpected %T", ErrRange, src)
+	}
+	*r = Range[T]{}
+	if text == "empty" {
+		r.Empty = true
+		return nil
+	}
+	if len(text) < 3 || !strings.ContainsRune("[(", rune(text[0])) || !strings.ContainsRune("])", rune(text[len(text)-1])) {
+		return fmt.Errorf("%w: %q", ErrRange, text)
+	}
+	r.LowerInc, r.UpperInc = text[0] == '[', text[len(text)-1] == ']'
+	attrs, err := parseComposite("(" + text[1:len(text)-1] + ")")
+	if err != nil || len(attrs) != 2 {
+		return fmt.Errorf("%w: %q", ErrRange, text)
+	}
+	if attrs[0] == nil {
+		r.LowerInf, r.LowerInc = true, false
+	} else if err = setText(reflect.ValueOf(&r.Lower).Elem(), *attrs[0]); err != nil {
+		return fmt.Errorf("%w: %v", ErrRange, err)
+	}
+	if attrs[1] == nil {
+		r.UpperInf, r.UpperInc = true, false
+	} else if err = setText(reflect.ValueOf(&r.Upper).Elem(), *attrs[1]); err != nil {
+		return fmt.Errorf("%w: %v", ErrRange, err)
+	}
+	return nil
+}
+
+func rangeBound(v any) string {
+	if t, ok := v.(time.Time); ok {
+		return `"` + t.Format("2006-01-02 15:04:05.999999999Z07:00") + `"`
+	}
+	return quoteCompositeAttr(fmt.Sprintf("%v", v))
+}
+
+// RangeOverlaps returns the condition "column && $1" with r as its argument.
+// Placeholders are numbered from $1, like every fragment passed to Where.
+func RangeOverlaps(column string, r any) (string, any) {
+	return column + " && $1", r
+}
+
+// RangeContains returns "column @> $1"; v may be a range or a single element.
+func RangeContains(column string, v any) (string, any) {
+	return column + " @> $1", v
+}
+
+// RangeContainedBy returns "column <@ $1".
+func RangeContainedBy(column string, r any) (string, any) {
+	return column + " <@ $1", r
+}
@@ -0,0 +1,113 @@
+package orm
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// ModelInfo describes a registered model.
+type ModelInfo struct {
+	Name    string
+	Package string
+	Table   string
+	Type    reflect.Type `json:"-"`
+	Columns []ColumnInfo
+}
+
+// ColumnInfo describes one mapped field of a model.
+type ColumnInfo struct {
+	Field   string
+	Column  string
+	GoType  string
+	Primary bool
+	// ReadOnly columns are generated or stamped on insert only (created_at)
+	// and are not meant to change afterwards.
+	ReadOnly bool
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]reflect.Type)
+)
+
+// Register records T so tooling (admin handlers, schema helpers) can discover
+// it by name. Registering the same type twice is a no-op; types of the same
+// name from different packages are kept apart.
+func Register[T any]() {
+	t := reflect.TypeOf(new(T)).Elem()
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[qualifiedName(t)] = t
+}
+
+// qualifiedName is the registry key of t, e.g. example.com/app/models.User.
+func qualifiedName(t reflect.Type) string {
+	return t.PkgPath() + "." + t.Name()
+}
+
+// Models returns the registered models sorted by name.
+func Models() []ModelInfo {
+	registryMu.RLock()
+	types := make([]reflect.Type, 0, len(registry))
+	for _, t := range registry {
+		types = append(types, t)
+	}
+	registryMu.RUnlock()
+	infos := make([]ModelInfo, 0, len(types))
+	for _, t := range types {
+		infos = append(infos, modelInfo(t))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Name != infos[j].Name {
+			return infos[i].Name < infos[j].Name
+		}
+		return infos[i].Package < infos[j].Package
+	})
+	return infos
+}
+
+// ModelOf returns the mapping of T, registered or not.
+func ModelOf[T any]() ModelInfo {
+	return modelInfo(reflect.TypeOf(new(T)).Elem())
+}
+
+// LookupModel returns the registered model with the given name. A bare type
+// name such as User only matches when a single registered model has it;
+// otherwise qualify it with the package path, example.com/app/models.User.
+func LookupModel(name string) (ModelInfo, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if t, ok := registry[name]; ok {
+		return modelInfo(t), true
+	}
+	var found reflect.Type
+	for _, t := range registry {
+		if t.Name() != name {
+			continue
+		}
+		if found != nil {
+			return ModelInfo{}, false
+		}
+		found = t
+	}
+	if found == nil {
+		return ModelInfo{}, false
+	}
+	return modelInfo(found), true
+}
+
+func modelInfo(t reflect.Type) ModelInfo {
+	m := modelOf(nil, t)
+	info := ModelInfo{Name: t.Name(), Package: t.PkgPath(), Table: getTableName(reflect.New(t).Interface()), Type: t}
+	for _, f := range m.Fields {
+		info.Columns = append(info.Columns, ColumnInfo{
+			Field:    f.Name,
+			Column:   f.Column,
+			GoType:   f.Type.String(),
+			Primary:  f.Primary,
+			ReadOnly: f.Generated != "" || (autoTimestamp(f.Column, false) && !autoTimestamp(f.Column, true)),
+		})
+	}
+	return info
+}
@@ -0,0 +1,293 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// relation is a struct field filled by Preload instead of a column, declared
+// with an orm tag such as:
+//
+//	Posts  []Post `orm:"hasMany:posts;fk:user_id"`
+//	Avatar *Image `orm:"hasOne;fk:user_id"`
+//	Team   *Team  `orm:"belongsTo;fk:team_id"`
+//	Roles  []Role `orm:"many2many:user_roles;fk:user_id;relatedFk:role_id"`
+//
+// fk is the foreign key column: on the related table for hasOne and
+// hasMany (default <owner>_id), on this table for belongsTo (default
+// <field>_id). references names the key it points at, the primary key by
+// default, and the optional table after the kind overrides the related
+// model's table. many2many names the join table instead, whose fk and
+// relatedFk columns (default <owner>_id and <related>_id) hold the primary
+// keys of both sides.
+type relation struct {
+	kind       string
+	table      string
+	join       string
+	fk         string
+	references string
+	relatedFk  string
+}
+
+func relationOf(sf reflect.StructField) (relation, bool) {
+	var rel relation
+	for _, part := range strings.Split(sf.Tag.Get("orm"), ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), ":")
+		switch key {
+		case "hasOne", "hasMany", "belongsTo":
+			rel.kind, rel.table = key, value
+		case "many2many":
+			rel.kind, rel.join = key, value
+		case "fk":
+			rel.fk = value
+		case "references":
+			rel.references = value
+		case "relatedFk":
+			rel.relatedFk = value
+		}
+	}
+	return rel, rel.kind != ""
+}
+
+// Preload fills the named relation fields of the rows returned by Query and
+// the helpers built on it, with one extra query per relation. Nested
+// relations are reached with dots: Preload("Posts.Comments").
+func Preload(fields ...string) QueryOption {
+	return queryOptionFunc(func(o *queryOptions) {
+		o.preload = append(o.preload, fields...)
+	})
+}
+
+// preload runs the Preload options for dest, a pointer to the scanned rows.
+func preload(ctx context.Context, ex Executor, h *handle, dest any, opts *queryOptions) error {
+	if len(opts.preload) == 0 {
+		return nil
+	}
+	parents := structValues(reflect.ValueOf(dest))
+	for _, path := range opts.preload {
+		if err := preloadPath(ctx, ex, h, parents, path, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// structValues returns the addressable structs held by v, looking through
+// pointers and slices.
+func structValues(v reflect.Value) []reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return structValues(v.Elem())
+	case reflect.Slice:
+		var list []reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			list = append(list, structValues(v.Index(i))...)
+		}
+		return list
+	case reflect.Struct:
+		return []reflect.Value{v}
+	}
+	return nil
+}
+
+func preloadPath(ctx context.Context, ex Executor, h *handle, parents []reflect.Value, path string, opts *queryOptions) error {
+	if len(parents) == 0 {
+		return nil
+	}
+	name, rest, _ := strings.Cut(path, ".")
+	parentType := parents[0].Type()
+	sf, ok := parentType.FieldByName(name)
+	rel, isRel := relationOf(sf)
+	if !ok || !isRel {
+		return fmt.Errorf("%w: %s has no relation %q", ErrFilter, parentType.Name(), name)
+	}
+	var loaded *relatedRows
+	var err error
+	if rel.kind == "many2many" {
+		loaded, err = loadMany2Many(ctx, ex, h, parents, sf, rel, opts)
+	} else {
+		loaded, err = loadRelated(ctx, ex, h, parents, sf, rel, opts)
+	}
+	if err != nil || loaded == nil {
+		return err
+	}
+
+	var children []reflect.Value
+	for _, p := range parents {
+		key, ok := relationKey(p.FieldByIndex(loaded.parentKey.Index))
+		if !ok {
+			continue
+		}
+		fv := p.FieldByIndex(sf.Index)
+		matches := loaded.byKey[key]
+		switch fv.Kind() {
+		case reflect.Slice:
+			s := reflect.MakeSlice(fv.Type(), 0, len(matches))
+			for _, i := range matches {
+				s = reflect.Append(s, relationValue(loaded.rows.Index(i), fv.Type().Elem()))
+			}
+			fv.Set(s)
+		default:
+			if len(matches) > 0 {
+				fv.Set(relationValue(loaded.rows.Index(matches[0]), fv.Type()))
+			}
+		}
+		children = append(children, structValues(fv)...)
+	}
+	if rest == "" {
+		return nil
+	}
+	return preloadPath(ctx, ex, h, children, rest, opts)
+}
+
+// relatedRows holds the rows loaded for a relation, grouped by the key of
+// the parent they belong to.
+type relatedRows struct {
+	parentKey *field
+	rows      reflect.Value
+	byKey     map[string][]int
+}
+
+// relatedType returns the model type held by a relation field.
+func relatedType(sf reflect.StructField) reflect.Type {
+	t := sf.Type
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t
+}
+
+// loadRelated loads a hasOne, hasMany or belongsTo relation of parents.
+func loadRelated(ctx context.Context, ex Executor, h *handle, parents []reflect.Value, sf reflect.StructField, rel relation, opts *queryOptions) (*relatedRows, error) {
+	parentType, childType := parents[0].Type(), relatedType(sf)
+	parentModel, childModel := modelOf(h, parentType), modelOf(h, childType)
+	parentCol, childCol := rel.references, rel.fk
+	if rel.kind == "belongsTo" {
+		parentCol, childCol = rel.fk, rel.references
+		if parentCol == "" {
+			parentCol = toSnake(sf.Name) + "_id"
+		}
+		if childCol == "" {
+			childCol = primaryKeyColumn(childModel)
+		}
+	} else {
+		if parentCol == "" {
+			parentCol = primaryKeyColumn(parentModel)
+		}
+		if childCol == "" {
+			childCol = toSnake(parentType.Name()) + "_id"
+		}
+	}
+	parentField, ok := parentModel.field(parentCol)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no column %q for %s", ErrFilter, parentType.Name(), parentCol, sf.Name)
+	}
+	childField, ok := childModel.field(childCol)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no column %q for %s", ErrFilter, childType.Name(), childCol, sf.Name)
+	}
+	keys := relationKeys(parents, parentField)
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	rows, err := queryRelated(ctx, ex, h, childType, rel.table, childCol, keys, opts)
+	if err != nil {
+		return nil, err
+	}
+	loaded := &relatedRows{parentKey: parentField, rows: rows, byKey: make(map[string][]int)}
+	for i := 0; i < rows.Len(); i++ {
+		if key, ok := relationKey(rows.Index(i).FieldByIndex(childField.Index)); ok {
+			loaded.byKey[key] = append(loaded.byKey[key], i)
+		}
+	}
+	return loaded, nil
+}
+
+// relationKeys returns the distinct non-nil values of f in parents.
+func relationKeys(parents []reflect.Value, f *field) []any {
+	var keys []any
+	seen := make(map[string]bool)
+	for _, p := range parents {
+		v := p.FieldByIndex(f.Index)
+		key, ok := relationKey(v)
+		if ok && !seen[key] {
+			seen[key] = true
+			keys = append(keys, reflect.Indirect(v).Interface())
+		}
+	}
+	return keys
+}
+
+// queryRelated selects the rows of t whose column is one of keys. table
+// overrides the table of t when set.
+func queryRelated(ctx context.Context, ex Executor, h *handle, t reflect.Type, table, column string, keys []any, opts *queryOptions) (reflect.Value, error) {
+	if table == "" {
+		table = quoteTable(h.sqlDialect(), rawTableName(reflect.New(t).Interface()))
+	}
+	where := scopedWhereOf(h, t, column+" IN ("+placeholders(1, len(keys))+")", opts)
+	sqlStr := fmt.Sprintf("SELECT * FROM %s WHERE %s", table, where)
+	start := time.Now()
+	query, args := rebind(h.sqlDialect(), sqlStr, keys)
+	rows, err := ex.QueryContext(ctx, query, args...)
+	if err != nil {
+		return reflect.Value{}, wrapQueryError(err, sqlStr, table)
+	}
+	list := reflect.New(reflect.SliceOf(t))
+	err = unmarshalSlice(h, rows, list.Interface())
+	rows.Close()
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	outputTimed(sqlStr, keys, start)
+	return list.Elem(), nil
+}
+
+// placeholders returns n comma separated markers starting at $from.
+func placeholders(from, n int) string {
+	marks := make([]string, n)
+	for i := range marks {
+		marks[i] = fmt.Sprintf("$%d", from+i)
+	}
+	return strings.Join(marks, ", ")
+}
+
+// relationKey returns the key held by v as a string, so that keys match
+// across integer types; nil pointers have none.
+func relationKey(v reflect.Value) (string, bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	if b, ok := v.Interface().([]byte); ok {
+		return string(b), true
+	}
+	return fmt.Sprint(v.Interface()), true
+}
+
+// relationValue returns a copy of the struct row as type t, T or *T.
+func relationValue(row reflect.Value, t reflect.Type) reflect.Value {
+	if t.Kind() == reflect.Pointer {
+		p := reflect.New(row.Type())
+		p.Elem().Set(row)
+		return p
+	}
+	return row
+}
+
+// primaryKeyColumn returns the first primary key column of m, else id.
+func primaryKeyColumn(m *model) string {
+	for _, f := range m.Fields {
+		if f.Primary {
+			return f.Column
+		}
+	}
+	return "id"
+}
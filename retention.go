@@ -0,0 +1,188 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+var ErrNoRetention = errors.New("retention: model has no field tagged retain")
+
+// RetentionPolicy is the retention a model declares with a retain tag on
+// the timestamp its rows age by:
+//
+//	type AuditEvent struct {
+//		ID         int64
+//		OccurredAt time.Time `retain:"90d"`
+//	}
+type RetentionPolicy struct {
+	Model  string        `json:"model"`
+	Table  string        `json:"table"`
+	Column string        `json:"column"`
+	Keep   time.Duration `json:"keep"`
+}
+
+// Retention collects the retention policies of models and purges the rows
+// past them. Unlike the Reaper it works from an explicit plan, which can be
+// reviewed or kept as an audit record before, or instead of, running it.
+//
+//	r := orm.NewRetention(db)
+//	orm.RegisterRetention[AuditEvent](r)
+//	plan, err := r.Plan(ctx)
+//	plan.WriteReport(os.Stdout)
+//	err = r.Purge(ctx, plan)
+type Retention struct {
+	DB *sql.DB
+	// BatchSize rows are deleted per statement (default 1000).
+	BatchSize int
+	// Pause is slept between batches to limit load (default 100ms).
+	Pause time.Duration
+
+	mu       sync.Mutex
+	policies []RetentionPolicy
+}
+
+// NewRetention returns a Retention using db.
+func NewRetention(db *sql.DB) *Retention {
+	return &Retention{DB: db}
+}
+
+// RegisterRetention adds the policy of T. T must have a field tagged retain
+// with a duration in the form ParseTTL accepts.
+func RegisterRetention[T any](r *Retention) error {
+	t := reflect.TypeOf(new(T)).Elem()
+	for _, f := range modelOf(lookupHandle(r.DB), t).Fields {
+		tag := f.Tag.Get("retain")
+		if tag == "" {
+			continue
+		}
+		keep, err := ParseTTL(tag)
+		if err != nil {
+			return fmt.Errorf("retention: %s.%s: %v", t.Name(), f.Name, err)
+		}
+		r.mu.Lock()
+		r.policies = append(r.policies, RetentionPolicy{Model: t.Name(), Table: quoteTable(lookupHandle(r.DB).sqlDialect(), rawTableName(new(T))), Column: f.Column, Keep: keep})
+		r.mu.Unlock()
+		return nil
+	}
+	return ErrNoRetention
+}
+
+// Policies returns the registered policies.
+func (r *Retention) Policies() []RetentionPolicy {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RetentionPolicy(nil), r.policies...)
+}
+
+// PurgeStep is the purge of one policy.
+type PurgeStep struct {
+	Policy RetentionPolicy `json:"policy"`
+	// Cutoff is the time before which rows are purged.
+	Cutoff time.Time `json:"cutoff"`
+	// Expired counts the rows past the cutoff when the plan was made and
+	// Oldest is the earliest of their timestamps.
+	Expired int64      `json:"expired"`
+	Oldest  *time.Time `json:"oldest,omitempty"`
+	// Deleted counts the rows Purge removed.
+	Deleted int64 `json:"deleted"`
+}
+
+// PurgePlan lists what a purge removes, per policy.
+type PurgePlan struct {
+	At    time.Time   `json:"at"`
+	Steps []PurgeStep `json:"steps"`
+	// Purged reports whether Purge ran the plan.
+	Purged bool `json:"purged"`
+}
+
+// Plan computes the cutoff of every policy from the handle's clock and
+// counts the rows past it, without deleting anything.
+func (r *Retention) Plan(ctx context.Context) (*PurgePlan, error) {
+	plan := &PurgePlan{At: lookupHandle(r.DB).now()}
+	for _, p := range r.Policies() {
+		step := PurgeStep{Policy: p, Cutoff: plan.At.Add(-p.Keep)}
+		sqlStr := fmt.Sprintf("SELECT count(*), min(%s) FROM %s WHERE %s < $1", p.Column, p.Table, p.Column)
+		start := time.Now()
+		var oldest sql.NullTime
+		if err := r.DB.QueryRowContext(ctx, sqlStr, step.Cutoff).Scan(&step.Expired, &oldest); err != nil {
+			return nil, wrapQueryError(err, sqlStr, p.Table)
+		}
+		outputTimed(sqlStr, []any{step.Cutoff}, start)
+		if oldest.Valid {
+			step.Oldest = &oldest.Time
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	return plan, nil
+}
+
+// Purge deletes the rows past the cutoffs of plan in rate limited batches,
+// recording the counts in its steps. Rows are removed for good, soft delete
+// or not. Rows that expired after the plan was made are left for the next
+// one.
+func (r *Retention) Purge(ctx context.Context, plan *PurgePlan) error {
+	batch := r.BatchSize
+	if batch <= 0 {
+		batch = 1000
+	}
+	pause := r.Pause
+	if pause <= 0 {
+		pause = 100 * time.Millisecond
+	}
+	for i := range plan.Steps {
+		step := &plan.Steps[i]
+		p := step.Policy
+		sqlStr := fmt.Sprintf("DELETE FROM %s WHERE ctid IN (SELECT ctid FROM %s WHERE %s < $1 LIMIT %d)", p.Table, p.Table, p.Column, batch)
+		for {
+			start := time.Now()
+			result, err := r.DB.ExecContext(ctx, sqlStr, step.Cutoff)
+			if err != nil {
+				return wrapQueryError(err, sqlStr, p.Table)
+			}
+			outputTimed(sqlStr, []any{step.Cutoff}, start)
+			n, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			step.Deleted += n
+			if n < int64(batch) {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(pause):
+			}
+		}
+	}
+	plan.Purged = true
+	return nil
+}
+
+// WriteReport writes plan as a table for audits: the policy, cutoff and
+// counts of every step.
+func (plan *PurgePlan) WriteReport(w io.Writer) error {
+	state := "planned"
+	if plan.Purged {
+		state = "purged"
+	}
+	fmt.Fprintf(w, "retention %s at %s\n", state, plan.At.Format(time.RFC3339))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODEL\tTABLE\tCOLUMN\tKEEP\tCUTOFF\tEXPIRED\tOLDEST\tDELETED")
+	for _, s := range plan.Steps {
+		oldest := "-"
+		if s.Oldest != nil {
+			oldest = s.Oldest.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%d\n", s.Policy.Model, s.Policy.Table, s.Policy.Column,
+			s.Policy.Keep, s.Cutoff.Format(time.RFC3339), s.Expired, oldest, s.Deleted)
+	}
+	return tw.Flush()
+}
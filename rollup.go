@@ -0,0 +1,105 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rollup keeps a summary table of T, such as daily counts, up to date: every
+// row Insert writes adds to the rollup row of its group in the same
+// transaction, with INSERT ... ON CONFLICT DO UPDATE. Updates and deletes
+// are not reflected, so rollups suit append-only tables like events.
+//
+//	orm.RegisterRollup(orm.Rollup[Order]{
+//		Table: "order_daily",
+//		Keys:  []string{"day", "shop_id"},
+//		Group: func(o Order) []any { return []any{o.CreatedAt.Format("2006-01-02"), o.ShopID} },
+//		Sums:  []string{"orders", "revenue"},
+//		Amounts: func(o Order) []any { return []any{1, o.Total} },
+//	})
+type Rollup[T any] struct {
+	// Table is the rollup table; it needs a unique index on Keys.
+	Table string
+	// Keys are the grouping columns of Table, filled from Group.
+	Keys  []string
+	Group func(row T) []any
+	// Sums are the columns of Table incremented by Amounts; a nil Amounts
+	// adds 1 to each, counting rows.
+	Sums    []string
+	Amounts func(row T) []any
+}
+
+type rollupFunc func(ctx context.Context, ex Executor, d Dialect, row any) error
+
+var (
+	rollupsMu sync.RWMutex
+	rollups   = make(map[reflect.Type][]rollupFunc)
+)
+
+// RegisterRollup adds r to the rollups Insert maintains for T.
+func RegisterRollup[T any](r Rollup[T]) {
+	fn := func(ctx context.Context, ex Executor, d Dialect, row any) error {
+		v := *row.(*T)
+		args := r.Group(v)
+		if len(args) != len(r.Keys) {
+			return fmt.Errorf("orm: rollup %s: Group returned %d values for %d keys", r.Table, len(args), len(r.Keys))
+		}
+		if r.Amounts == nil {
+			for range r.Sums {
+				args = append(args, 1)
+			}
+		} else {
+			amounts := r.Amounts(v)
+			if len(amounts) != len(r.Sums) {
+				return fmt.Errorf("orm: rollup %s: Amounts returned %d values for %d sums", r.Table, len(amounts), len(r.Sums))
+			}
+			args = append(args, amounts...)
+		}
+		sqlStr := r.upsertSql(d)
+		start := time.Now()
+		query, queryArgs := rebind(d, sqlStr, args)
+		if _, err := ex.ExecContext(ctx, query, queryArgs...); err != nil {
+			return fmt.Errorf("orm: rollup %s: %w", r.Table, err)
+		}
+		outputTimed(sqlStr, args, start)
+		return nil
+	}
+	t := reflect.TypeOf(new(T)).Elem()
+	rollupsMu.Lock()
+	rollups[t] = append(rollups[t], fn)
+	rollupsMu.Unlock()
+}
+
+func (r Rollup[T]) upsertSql(d Dialect) string {
+	columns := append(append([]string{}, r.Keys...), r.Sums...)
+	set := make([]string, len(r.Sums))
+	if d.Name() == "mysql" {
+		for i, c := range r.Sums {
+			set[i] = fmt.Sprintf("%s = %s + VALUES(%s)", c, c, c)
+		}
+		return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s",
+			r.Table, strings.Join(columns, ", "), placeholders(1, len(columns)), strings.Join(set, ", "))
+	}
+	for i, c := range r.Sums {
+		set[i] = fmt.Sprintf("%s = r.%s + EXCLUDED.%s", c, c, c)
+	}
+	return fmt.Sprintf("INSERT INTO %s AS r (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s",
+		r.Table, strings.Join(columns, ", "), placeholders(1, len(columns)), strings.Join(r.Keys, ", "), strings.Join(set, ", "))
+}
+
+// applyRollups runs the rollups registered for the type row points to.
+func applyRollups(ctx context.Context, ex Executor, h *handle, row any) error {
+	rollupsMu.RLock()
+	fns := rollups[reflect.TypeOf(row).Elem()]
+	rollupsMu.RUnlock()
+	for _, fn := range fns {
+		if err := fn(ctx, ex, h.sqlDialect(), row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// sampleThreshold is the estimated table size from which QuerySample reads
+// a TABLESAMPLE instead of sorting the whole table.
+const sampleThreshold = 10000
+
+// SampleWeight makes QuerySample pick rows with probability proportional to
+// column, a positive number.
+func SampleWeight(column string) QueryOption {
+	return queryOptionFunc(func(o *queryOptions) {
+		o.sampleWeight = column
+	})
+}
+
+// QuerySample returns up to n random rows of T's table matching where. On
+// Postgres large unweighted tables are read through TABLESAMPLE BERNOULLI,
+// oversampled from the planner's row estimate, falling back to a full
+// ORDER BY random() when the sample comes up short; other dialects and
+// weighted samples always sort. Soft deleted rows are left out unless args
+// hold Unscoped.
+func QuerySample[T any](ctx context.Context, db Executor, n int, where string, args ...any) ([]T, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	db = route[T](db)
+	h := handleFor(db)
+	_, opts := splitOptions(args)
+	table := opts.tableName(h, new(T))
+	random := "random()"
+	if h.sqlDialect().Name() == "mysql" {
+		random = "RAND()"
+	}
+	order := random
+	if opts.sampleWeight != "" {
+		f, ok := modelOf(h, reflect.TypeOf(new(T)).Elem()).selectable(opts.sampleWeight)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrFilter, opts.sampleWeight)
+		}
+		// Efraimidis-Spirakis: the n smallest -ln(u)/w are a weighted sample.
+		order = fmt.Sprintf("-LN(1 - %s) / %s", random, f.Column)
+		if where != "" {
+			where = "(" + where + ") AND "
+		}
+		where += f.Column + " > 0"
+	}
+	where = scopedWhere[T](h, where, opts)
+	if where != "" {
+		where = " WHERE " + where
+	}
+	if h.sqlDialect() == Postgres && opts.sampleWeight == "" {
+		if estimate, err := Query[float64](ctx, db, "SELECT reltuples::float8 FROM pg_class WHERE oid = $1::regclass", table); err == nil && *estimate > sampleThreshold {
+			percent := 300 * float64(n) / *estimate
+			if percent < 100 {
+				sqlStr := fmt.Sprintf("SELECT * FROM %s TABLESAMPLE BERNOULLI (%g)%s ORDER BY %s LIMIT %d", table, percent, where, order, n)
+				rows, err := Query[[]T](ctx, db, sqlStr, args...)
+				if err != nil {
+					return nil, err
+				}
+				if len(*rows) == n {
+					return *rows, nil
+				}
+			}
+		}
+	}
+	sqlStr := fmt.Sprintf("SELECT * FROM %s%s ORDER BY %s LIMIT %d", table, where, order, n)
+	rows, err := Query[[]T](ctx, db, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	return *rows, nil
+}
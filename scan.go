@@ -0,0 +1,102 @@
+package orm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"time"
+)
+
+// rowScanner is the subset of *sql.Rows needed to scan a row.
+type rowScanner interface {
+	Columns() ([]string, error)
+	Scan(dest ...any) error
+}
+
+// scanPlan maps result columns onto the fields of a model.
+type scanPlan struct {
+	fields []*field
+}
+
+func newScanPlan(m *model, columns []string) *scanPlan {
+	p := &scanPlan{fields: make([]*field, len(columns))}
+	for i, column := range columns {
+		if f, ok := m.field(column); ok {
+			p.fields[i] = f
+		}
+	}
+	return p
+}
+
+// scan reads the current row into dest, which must be an addressable struct.
+// Columns without a matching field are discarded.
+func (p *scanPlan) scan(rows rowScanner, dest reflect.Value) error {
+	targets := make([]any, len(p.fields))
+	var nullable []reflect.Value
+	for i, f := range p.fields {
+		if f == nil {
+			targets[i] = new(any)
+			continue
+		}
+		fv := dest.FieldByIndex(f.Index)
+		if f.Composite == "" && f.Serializer == "" && !acceptsNull(fv) {
+			// Scan through a pointer so NULL leaves the zero value instead
+			// of failing; database/sql still does the conversion.
+			ptr := reflect.New(reflect.PointerTo(fv.Type()))
+			targets[i] = ptr.Interface()
+			nullable = append(nullable, fv, ptr.Elem())
+			continue
+		}
+		targets[i] = f.scanTarget(fv)
+	}
+	if err := rows.Scan(targets...); err != nil {
+		return err
+	}
+	for i := 0; i < len(nullable); i += 2 {
+		fv, ptr := nullable[i], nullable[i+1]
+		if ptr.IsNil() {
+			fv.Set(reflect.Zero(fv.Type()))
+		} else {
+			fv.Set(ptr.Elem())
+		}
+	}
+	return nil
+}
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// acceptsNull reports whether NULL can be scanned into fv directly:
+// pointers, interfaces, byte slices, sql.Scanner implementations and types
+// with a registered converter.
+func acceptsNull(fv reflect.Value) bool {
+	if _, ok := convertedField(fv.Type()); ok {
+		return true
+	}
+	switch fv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return true
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			return true
+		}
+	}
+	return reflect.PointerTo(fv.Type()).Implements(scannerType)
+}
+
+// scanTarget returns the value handed to rows.Scan for the field fv.
+func (f *field) scanTarget(fv reflect.Value) any {
+	if f.Composite != "" {
+		return &compositeScanner{v: fv}
+	}
+	if f.Serializer != "" {
+		return &serializedScanner{v: fv, name: f.Serializer}
+	}
+	if conv, ok := convertedField(fv.Type()); ok {
+		return &convertedScanner{v: fv, conv: conv}
+	}
+	return fv.Addr().Interface()
+}
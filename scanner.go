@@ -0,0 +1,108 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+)
+
+var ErrScanDest = errors.New("scan: dest must be a struct pointer or a pointer to a slice of structs")
+
+// RowsScanner wraps *sql.Rows with the sqlx scanning methods, mapping columns
+// with the same rules as Query.
+type RowsScanner struct {
+	*sql.Rows
+	h    *handle
+	plan *scanPlan
+	typ  reflect.Type
+}
+
+// NewRowsScanner wraps rows. db selects the handle settings used for column
+// mapping and may be nil.
+func NewRowsScanner(db *sql.DB, rows *sql.Rows) *RowsScanner {
+	return &RowsScanner{Rows: rows, h: lookupHandle(db)}
+}
+
+// StructScan scans the current row into dest, a pointer to a struct.
+func (r *RowsScanner) StructScan(dest any) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return ErrScanDest
+	}
+	v = v.Elem()
+	if r.plan == nil || r.typ != v.Type() {
+		columns, err := r.Columns()
+		if err != nil {
+			return err
+		}
+		r.plan = newScanPlan(modelOf(r.h, v.Type()), columns)
+		r.typ = v.Type()
+	}
+	return r.plan.scan(r.Rows, v)
+}
+
+// MapScan scans the current row into dest keyed by column name.
+func (r *RowsScanner) MapScan(dest map[string]any) error {
+	columns, err := r.Columns()
+	if err != nil {
+		return err
+	}
+	values, err := r.SliceScan()
+	if err != nil {
+		return err
+	}
+	for i, column := range columns {
+		dest[column] = values[i]
+	}
+	return nil
+}
+
+// SliceScan scans the current row into a slice of driver values.
+func (r *RowsScanner) SliceScan() ([]any, error) {
+	columns, err := r.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]any, len(columns))
+	targets := make([]any, len(columns))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+	if err = r.Scan(targets...); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// GetContext runs the query and scans the first row into dest, a struct
+// pointer. Like sqlx it returns sql.ErrNoRows when nothing matched.
+func GetContext(ctx context.Context, db Executor, dest any, query string, args ...any) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	return (&RowsScanner{Rows: rows, h: handleFor(db)}).StructScan(dest)
+}
+
+// SelectContext runs the query and appends every row to dest, a pointer to a
+// slice of structs.
+func SelectContext(ctx context.Context, db Executor, dest any, query string, args ...any) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Slice || v.Elem().Type().Elem().Kind() != reflect.Struct {
+		return ErrScanDest
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return unmarshalSlice(handleFor(db), rows, dest)
+}
@@ -0,0 +1,88 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// advisoryClass is the first key of every advisory lock taken by the ORM, so
+// they never collide with single-key locks taken by the application.
+const advisoryClass = 0x6f726d
+
+const createJobsSql = `CREATE TABLE IF NOT EXISTS orm_jobs (
+	name text PRIMARY KEY,
+	last_run timestamptz NOT NULL,
+	last_error text NOT NULL DEFAULT '',
+	runs bigint NOT NULL DEFAULT 0
+)`
+
+const claimJobSql = `INSERT INTO orm_jobs (name, last_run) VALUES ($1, now())
+ON CONFLICT (name) DO UPDATE SET last_run = now()
+WHERE orm_jobs.last_run <= now() - make_interval(secs => $2::float8)
+RETURNING name`
+
+// Schedule runs fn every interval on exactly one of the processes sharing db
+// until ctx is done. An advisory lock prevents overlapping runs and the
+// orm_jobs table (created on first use) records the last run, so replicas
+// with skewed tickers still run the job once per interval. Errors returned
+// by fn are logged and stored in orm_jobs.last_error.
+func Schedule(ctx context.Context, db *sql.DB, name string, every time.Duration, fn func(ctx context.Context) error) error {
+	if every <= 0 {
+		return errors.New("schedule: interval must be positive")
+	}
+	if _, err := db.ExecContext(ctx, createJobsSql); err != nil {
+		return err
+	}
+	check := every / 10
+	if check < time.Second {
+		check = time.Second
+	}
+	if check > time.Minute {
+		check = time.Minute
+	}
+	ticker := time.NewTicker(check)
+	defer ticker.Stop()
+	for {
+		if err := runJob(ctx, db, name, every, fn); err != nil && ctx.Err() == nil {
+			log.Printf("[ORM ERROR]\t job %s: %v\n", name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func runJob(ctx context.Context, db *sql.DB, name string, every time.Duration, fn func(ctx context.Context) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var locked bool
+	if err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1, hashtext($2))", advisoryClass, name).Scan(&locked); err != nil || !locked {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1, hashtext($2))", advisoryClass, name)
+	var claimed string
+	err = conn.QueryRowContext(ctx, claimJobSql, name, every.Seconds()).Scan(&claimed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	runErr := fn(ctx)
+	message := ""
+	if runErr != nil {
+		message = runErr.Error()
+	}
+	if _, err = conn.ExecContext(ctx, "UPDATE orm_jobs SET runs = runs + 1, last_error = $2 WHERE name = $1", name, message); err != nil {
+		return err
+	}
+	return runErr
+}
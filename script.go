@@ -0,0 +1,117 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Statement is one statement of a SQL script.
+type Statement struct {
+	SQL string
+	// Line is the script line the statement starts on, counting from 1.
+	Line int
+}
+
+// SplitScript splits a script on the semicolons outside strings, quoted
+// identifiers, dollar-quoted bodies and comments. Comments before a
+// statement and empty statements are dropped.
+func SplitScript(script string) []Statement {
+	var (
+		stmts []Statement
+		b     strings.Builder
+		line  int
+	)
+	flush := func() {
+		if sqlStr := strings.TrimSpace(b.String()); sqlStr != "" && line > 0 {
+			stmts = append(stmts, Statement{SQL: sqlStr, Line: line})
+		}
+		b.Reset()
+		line = 0
+	}
+	for _, tok := range lexSQL(script) {
+		if tok.kind == tokPunct && tok.text == ";" {
+			flush()
+			continue
+		}
+		if line == 0 && tok.kind != tokSpace && tok.kind != tokComment {
+			line = tok.line
+		}
+		if line > 0 {
+			b.WriteString(tok.text)
+		}
+	}
+	flush()
+	return stmts
+}
+
+// ScriptOptions controls RunScript.
+type ScriptOptions struct {
+	// PerStatement runs every statement on its own instead of in one
+	// transaction, as CREATE INDEX CONCURRENTLY and VACUUM require. Earlier
+	// statements stay applied when a later one fails.
+	PerStatement bool
+	// ContinueOnError keeps running the remaining statements after a failure
+	// in PerStatement mode; the first error is returned.
+	ContinueOnError bool
+}
+
+// ScriptError reports the statement of a script that failed.
+type ScriptError struct {
+	Index     int
+	Line      int
+	Statement string
+	Err       error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("script: statement %d (line %d): %v", e.Index+1, e.Line, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// RunScript runs the statements of script in order, by default all in one
+// transaction (a savepoint within a caller's *sql.Tx). A failure is returned
+// as a *ScriptError naming the statement and its line.
+func RunScript(ctx context.Context, db Executor, script string, opts ScriptOptions) error {
+	stmts := SplitScript(script)
+	if opts.PerStatement {
+		var first error
+		for i, stmt := range stmts {
+			if err := runStatement(ctx, db, i, stmt); err != nil {
+				if !opts.ContinueOnError {
+					return err
+				}
+				if first == nil {
+					first = err
+				}
+			}
+		}
+		return first
+	}
+	tx, err := beginTx(ctx, db)
+	if err != nil {
+		return err
+	}
+	for i, stmt := range stmts {
+		if err = runStatement(ctx, tx, i, stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func runStatement(ctx context.Context, ex Executor, i int, stmt Statement) error {
+	start := time.Now()
+	if _, err := ex.ExecContext(ctx, stmt.SQL); err != nil {
+		log.Printf("[ORM ERROR]\t script statement %d (line %d): %v\n", i+1, stmt.Line, err)
+		return &ScriptError{Index: i, Line: stmt.Line, Statement: stmt.SQL, Err: err}
+	}
+	outputTimed(stmt.SQL, nil, start)
+	return nil
+}
@@ -0,0 +1,169 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var ErrFKCycle = errors.New("foreign keys form a cycle")
+
+// TableOrder sorts tables so that every table comes after the tables it
+// references through foreign keys, as read from pg_constraint. Tables keep
+// their given order where no dependency applies; self references are
+// ignored.
+func TableOrder(ctx context.Context, db Executor, tables ...string) ([]string, error) {
+	canonical := make(map[string]string, len(tables))
+	rows, err := db.QueryContext(ctx, "SELECT t, t::regclass::text FROM unnest($1::text[]) AS t", pq.Array(tables))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var name, reg string
+		if err = rows.Scan(&name, &reg); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		canonical[reg] = name
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	deps := make(map[string]map[string]bool)
+	rows, err = db.QueryContext(ctx, `SELECT conrelid::regclass::text, confrelid::regclass::text
+FROM pg_constraint WHERE contype = 'f' AND conrelid <> confrelid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var from, to string
+		if err = rows.Scan(&from, &to); err != nil {
+			return nil, err
+		}
+		child, ok1 := canonical[from]
+		parent, ok2 := canonical[to]
+		if !ok1 || !ok2 {
+			continue
+		}
+		if deps[child] == nil {
+			deps[child] = make(map[string]bool)
+		}
+		deps[child][parent] = true
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return sortByDeps(tables, deps)
+}
+
+// sortByDeps is a stable topological sort: each round emits, in input order,
+// every table whose dependencies were all emitted.
+func sortByDeps(tables []string, deps map[string]map[string]bool) ([]string, error) {
+	done := make(map[string]bool, len(tables))
+	out := make([]string, 0, len(tables))
+	for len(out) < len(tables) {
+		progress := false
+		for _, t := range tables {
+			if done[t] {
+				continue
+			}
+			ready := true
+			for parent := range deps[t] {
+				ready = ready && done[parent]
+			}
+			if ready {
+				done[t] = true
+				out = append(out, t)
+				progress = true
+			}
+		}
+		if !progress {
+			var left []string
+			for _, t := range tables {
+				if !done[t] {
+					left = append(left, t)
+				}
+			}
+			sort.Strings(left)
+			return nil, fmt.Errorf("%w: %s", ErrFKCycle, strings.Join(left, ", "))
+		}
+	}
+	return out, nil
+}
+
+// SeedSet is a batch of rows for one table, created with SeedRows.
+type SeedSet interface {
+	table() string
+	insert(ctx context.Context, db Executor) error
+}
+
+type seedRows[T any] struct {
+	rows    []T
+	options []QueryOption
+}
+
+// SeedRows prepares rows of T for Seed.
+func SeedRows[T any](rows []T, options ...QueryOption) SeedSet {
+	return seedRows[T]{rows: rows, options: options}
+}
+
+func (s seedRows[T]) table() string {
+	return applyOptions(s.options).tableName(nil, new(T))
+}
+
+func (s seedRows[T]) insert(ctx context.Context, db Executor) error {
+	_, err := Insert(ctx, db, s.rows, s.options...)
+	return err
+}
+
+// Seed inserts every set in one transaction, parents before children, so the
+// caller need not order sets by their foreign keys.
+func Seed(ctx context.Context, db Executor, sets ...SeedSet) error {
+	return Transaction(ctx, db, func(tx Executor) error {
+		byTable := make(map[string][]SeedSet)
+		var tables []string
+		for _, s := range sets {
+			if _, ok := byTable[s.table()]; !ok {
+				tables = append(tables, s.table())
+			}
+			byTable[s.table()] = append(byTable[s.table()], s)
+		}
+		order, err := TableOrder(ctx, tx, tables...)
+		if err != nil {
+			return err
+		}
+		for _, table := range order {
+			for _, s := range byTable[table] {
+				if err = s.insert(ctx, tx); err != nil {
+					return fmt.Errorf("seed %s: %w", table, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// ClearTables deletes every row of tables in one transaction, children before
+// parents.
+func ClearTables(ctx context.Context, db Executor, tables ...string) error {
+	return Transaction(ctx, db, func(tx Executor) error {
+		order, err := TableOrder(ctx, tx, tables...)
+		if err != nil {
+			return err
+		}
+		for i := len(order) - 1; i >= 0; i-- {
+			sqlStr := "DELETE FROM " + order[i]
+			outputSql(sqlStr, nil)
+			if _, err = tx.ExecContext(ctx, sqlStr); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,43 @@
+package orm
+
+import (
+	"context"
+	"time"
+)
+
+// NextID reserves the next value of sequence.
+func NextID(ctx context.Context, db Executor, sequence string) (int64, error) {
+	id, err := Query[int64](ctx, db, "SELECT nextval($1::regclass)", sequence)
+	if err != nil {
+		return 0, err
+	}
+	return *id, nil
+}
+
+// NextIDs reserves n values of sequence in a single round trip, for assigning
+// ids client side before a bulk insert.
+func NextIDs(ctx context.Context, db Executor, sequence string, n int) ([]int64, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	sqlStr := "SELECT nextval($1::regclass) FROM generate_series(1, $2)"
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, sqlStr, sequence, n)
+	if err != nil {
+		return nil, wrapQueryError(err, sqlStr, "")
+	}
+	defer rows.Close()
+	ids := make([]int64, 0, n)
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	outputTimed(sqlStr, []any{sequence, n}, start)
+	return ids, nil
+}
@@ -0,0 +1,64 @@
+package orm
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Serializer encodes a field into a single column, selected with the
+// serializer:"<name>" tag.
+type Serializer struct {
+	Marshal   func(v any) ([]byte, error)
+	Unmarshal func(data []byte, v any) error
+}
+
+var (
+	serializerMu sync.RWMutex
+	serializers  = map[string]Serializer{
+		"json": {Marshal: json.Marshal, Unmarshal: json.Unmarshal},
+		"xml":  {Marshal: xml.Marshal, Unmarshal: xml.Unmarshal},
+	}
+)
+
+// RegisterSerializer makes s available as serializer:"<name>".
+func RegisterSerializer(name string, s Serializer) {
+	serializerMu.Lock()
+	defer serializerMu.Unlock()
+	serializers[name] = s
+}
+
+func serializerOf(name string) (Serializer, bool) {
+	serializerMu.RLock()
+	defer serializerMu.RUnlock()
+	s, ok := serializers[name]
+	return s, ok
+}
+
+// serializedScanner decodes a column into a field with a Serializer.
+type serializedScanner struct {
+	v    reflect.Value
+	name string
+}
+
+func (s *serializedScanner) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		s.v.Set(reflect.Zero(s.v.Type()))
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("orm: cannot deserialize %T with %s", src, s.name)
+	}
+	ser, ok := serializerOf(s.name)
+	if !ok {
+		return fmt.Errorf("orm: unknown serializer %q", s.name)
+	}
+	return ser.Unmarshal(data, s.v.Addr().Interface())
+}
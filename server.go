@@ -0,0 +1,143 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var ErrUnsupported = errors.New("orm: feature not supported by the server")
+
+// Feature is a server capability some helpers depend on.
+type Feature string
+
+const (
+	FeatureOnConflict       Feature = "ON CONFLICT"
+	FeatureGeneratedColumns Feature = "generated columns"
+	FeatureMerge            Feature = "MERGE"
+)
+
+// featureVersions holds the first server_version_num providing a feature.
+var featureVersions = map[Feature]int{
+	FeatureOnConflict:       90500,
+	FeatureGeneratedColumns: 120000,
+	FeatureMerge:            150000,
+}
+
+// Server describes the connected Postgres server.
+type Server struct {
+	Version    string
+	VersionNum int
+	Extensions map[string]string
+	Settings   map[string]string
+}
+
+// Supports reports whether the server provides f.
+func (s *Server) Supports(f Feature) bool {
+	since, ok := featureVersions[f]
+	return ok && s.VersionNum >= since
+}
+
+// Require returns an ErrUnsupported error naming the missing feature.
+func (s *Server) Require(f Feature) error {
+	if s.Supports(f) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s needs server_version_num %d, connected to %s", ErrUnsupported, f, featureVersions[f], s.Version)
+}
+
+// HasExtension reports whether the extension is installed in the database.
+func (s *Server) HasExtension(name string) bool {
+	_, ok := s.Extensions[name]
+	return ok
+}
+
+// ServerInfo returns the version, installed extensions and settings of the
+// server behind db. The result is cached per handle until InvalidateCaches.
+func ServerInfo(ctx context.Context, db *sql.DB) (*Server, error) {
+	return serverFor(ctx, db)
+}
+
+// serverFor returns the server behind ex, cached on the owning handle when
+// ex is a *sql.DB or bound with Use.
+func serverFor(ctx context.Context, ex Executor) (*Server, error) {
+	var h *handle
+	switch e := ex.(type) {
+	case *sql.DB:
+		h = handleOf(e)
+	case *boundExecutor:
+		h = handleOf(e.db)
+	}
+	if s := h.cachedServer(); s != nil {
+		return s, nil
+	}
+	s, err := loadServer(ctx, ex)
+	if err != nil {
+		return nil, err
+	}
+	if h != nil {
+		h.setServer(s)
+	}
+	return s, nil
+}
+
+// serverVersion returns the server behind ex with only its version filled
+// in, which is all feature gates need. It is cached on the handle of ex.
+func serverVersion(ctx context.Context, ex Executor) (*Server, error) {
+	h := handleFor(ex)
+	if s := h.cachedVersion(); s != nil {
+		return s, nil
+	}
+	s := &Server{}
+	if err := loadVersion(ctx, ex, s); err != nil {
+		return nil, err
+	}
+	if h != nil {
+		h.setVersion(s)
+	}
+	return s, nil
+}
+
+func loadVersion(ctx context.Context, db Executor, s *Server) error {
+	return db.QueryRowContext(ctx, "SELECT current_setting('server_version'), current_setting('server_version_num')::int").Scan(&s.Version, &s.VersionNum)
+}
+
+func loadServer(ctx context.Context, db Executor) (*Server, error) {
+	s := &Server{Extensions: make(map[string]string), Settings: make(map[string]string)}
+	if err := loadVersion(ctx, db, s); err != nil {
+		return nil, err
+	}
+	if err := scanPairs(ctx, db, "SELECT extname, extversion FROM pg_extension", s.Extensions); err != nil {
+		return nil, err
+	}
+	if err := scanPairs(ctx, db, "SELECT name, setting FROM pg_settings", s.Settings); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RequireFeature fails with ErrUnsupported when the server behind db lacks f.
+func RequireFeature(ctx context.Context, db *sql.DB, f Feature) error {
+	s, err := serverVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	return s.Require(f)
+}
+
+func scanPairs(ctx context.Context, db Executor, sqlStr string, dest map[string]string) error {
+	rows, err := db.QueryContext(ctx, sqlStr)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var k, v string
+		if err = rows.Scan(&k, &v); err != nil {
+			return err
+		}
+		dest[k] = v
+	}
+	return rows.Err()
+}
@@ -0,0 +1,61 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// sessionVar maps a context key to a custom server setting.
+type sessionVar struct {
+	name string
+	key  any
+}
+
+// SessionVar makes each transaction the ORM starts on db (Insert, Update,
+// Transaction, Plan and so on) set the custom setting name, e.g.
+// "app.user_id", to the value ctx holds under key, so triggers and row level
+// security policies can read the request identity:
+//
+//	orm.SessionVar(db, "app.user_id", userIDKey{})
+//	orm.SessionVar(db, "app.request_id", requestIDKey{})
+//
+//	CREATE POLICY own_rows ON notes
+//		USING (owner_id = current_setting('app.user_id', true)::bigint);
+//
+// Settings are applied with SET LOCAL semantics and vanish when the
+// transaction ends. Keys missing from ctx are skipped. Query runs in a
+// transaction of its own when ctx holds any of the keys.
+func SessionVar(db *sql.DB, name string, key any) {
+	h := handleOf(db)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, v := range h.sessionVars {
+		if v.name == name {
+			h.sessionVars[i].key = key
+			return
+		}
+	}
+	h.sessionVars = append(h.sessionVars, sessionVar{name: name, key: key})
+}
+
+// sessionSettings returns the settings ctx yields for h's session vars.
+func (h *handle) sessionSettings(ctx context.Context) map[string]string {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var settings map[string]string
+	for _, v := range h.sessionVars {
+		value := ctx.Value(v.key)
+		if value == nil {
+			continue
+		}
+		if settings == nil {
+			settings = make(map[string]string)
+		}
+		settings[v.name] = fmt.Sprint(value)
+	}
+	return settings
+}
@@ -0,0 +1,98 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PlannerSettings is implemented by models whose queries need server
+// settings, e.g. {"work_mem": "256MB"} for a heavy report. They apply to
+// every Query of the model; WithSettings overrides them per call.
+type PlannerSettings interface {
+	PlannerSettings() map[string]string
+}
+
+// WithSettings runs the statement in its own transaction with the given
+// settings applied locally (SET LOCAL), such as enable_seqscan=off. Inside a
+// caller's *sql.Tx they last until that transaction ends.
+func WithSettings(settings map[string]string) QueryOption {
+	return queryOptionFunc(func(o *queryOptions) {
+		if o.settings == nil {
+			o.settings = make(map[string]string)
+		}
+		for name, value := range settings {
+			o.settings[name] = value
+		}
+	})
+}
+
+// WithHint prefixes the statement with a planner hint comment, read by
+// extensions such as pg_hint_plan: WithHint("SeqScan(users)").
+func WithHint(hint string) QueryOption {
+	return queryOptionFunc(func(o *queryOptions) {
+		o.hints = append(o.hints, hint)
+	})
+}
+
+// hinted prepends the hint comment to sqlStr.
+func (o *queryOptions) hinted(sqlStr string) string {
+	if len(o.hints) == 0 {
+		return sqlStr
+	}
+	hints := strings.ReplaceAll(strings.Join(o.hints, " "), "*/", "")
+	return "/*+ " + hints + " */ " + sqlStr
+}
+
+// plannerSettings merges the model settings of dest with the call's own.
+func (o *queryOptions) plannerSettings(dest any) map[string]string {
+	t := reflect.TypeOf(dest).Elem()
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	var settings map[string]string
+	if ps, ok := reflect.New(t).Interface().(PlannerSettings); ok {
+		settings = ps.PlannerSettings()
+	}
+	if len(o.settings) == 0 {
+		return settings
+	}
+	merged := make(map[string]string, len(settings)+len(o.settings))
+	for name, value := range settings {
+		merged[name] = value
+	}
+	for name, value := range o.settings {
+		merged[name] = value
+	}
+	return merged
+}
+
+// applySettings sets each setting for the rest of the current transaction.
+// Settings are a Postgres feature; other dialects get ErrUnsupported.
+func applySettings(ctx context.Context, h *handle, ex Executor, settings map[string]string) error {
+	d := h.sqlDialect()
+	if len(settings) == 0 {
+		return nil
+	}
+	if d.Name() != "postgres" {
+		return fmt.Errorf("%w: settings and session vars need Postgres, not %s", ErrUnsupported, d.Name())
+	}
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	const sqlStr = "SELECT set_config($1, $2, true)"
+	for _, name := range names {
+		start := time.Now()
+		query, args := rebind(d, sqlStr, []any{name, settings[name]})
+		if _, err := ex.ExecContext(ctx, query, args...); err != nil {
+			return wrapQueryError(err, sqlStr, "")
+		}
+		outputTimed(sqlStr, []any{name, settings[name]}, start)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package orm
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// maxShadowDiffs caps the paths reported for one comparison.
+const maxShadowDiffs = 20
+
+// ShadowRead replays sampled reads against a second data source and reports
+// differences, to validate a migration or refactor before cutover.
+type ShadowRead struct {
+	Target *sql.DB
+	// SampleRate is the fraction of reads replayed, from 0 to 1.
+	SampleRate float64
+	// Timeout bounds each replay; it defaults to 5s.
+	Timeout time.Duration
+	// OnDiff is called for every mismatch in addition to logging.
+	OnDiff func(ShadowDiff)
+}
+
+// ShadowDiff describes one read whose results differed.
+type ShadowDiff struct {
+	SQL  string
+	Args []any
+	// Paths lists differing locations such as "[3].email" or "len".
+	Paths []string
+	// Err is set when the shadow read failed.
+	Err error
+}
+
+// EnableShadowReads replays sampled Query calls on db against cfg.Target in
+// the background. Results are compared in their JSON form, before any
+// AfterScan hooks run.
+func EnableShadowReads(db *sql.DB, cfg ShadowRead) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	h := handleOf(db)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.shadow = &cfg
+}
+
+// DisableShadowReads stops replaying reads made through db.
+func DisableShadowReads(db *sql.DB) {
+	if h := lookupHandle(db); h != nil {
+		h.mu.Lock()
+		h.shadow = nil
+		h.mu.Unlock()
+	}
+}
+
+// shadowQuery replays a sampled Query on the shadow target. The primary
+// result is snapshotted before returning so the caller may keep using it.
+func shadowQuery[T any](h *handle, sqlStr string, args []any, primary *T) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	cfg := h.shadow
+	h.mu.RUnlock()
+	if cfg == nil || rand.Float64() >= cfg.SampleRate {
+		return
+	}
+	want, err := json.Marshal(primary)
+	if err != nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+		defer cancel()
+		diff := ShadowDiff{SQL: sqlStr, Args: args}
+		shadow, err := Query[T](ctx, cfg.Target, sqlStr, args...)
+		var got []byte
+		if err == nil {
+			got, err = json.Marshal(shadow)
+		}
+		if err != nil {
+			diff.Err = err
+		} else if bytes.Equal(want, got) {
+			return
+		} else {
+			diff.Paths = jsonDiff(want, got)
+		}
+		log.Printf("[ORM WARN]\t shadow read differs: %s paths=%v err=%v\n", sqlStr, diff.Paths, diff.Err)
+		if cfg.OnDiff != nil {
+			cfg.OnDiff(diff)
+		}
+	}()
+}
+
+// jsonDiff returns the paths at which two JSON documents differ.
+func jsonDiff(a, b []byte) []string {
+	var va, vb any
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return []string{""}
+	}
+	var out []string
+	diffJSON(va, vb, "", &out)
+	return out
+}
+
+func diffJSON(a, b any, path string, out *[]string) {
+	if len(*out) >= maxShadowDiffs {
+		return
+	}
+	switch x := a.(type) {
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		for k := range y {
+			if _, ok := x[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sub := k
+			if path != "" {
+				sub = path + "." + k
+			}
+			diffJSON(x[k], y[k], sub, out)
+		}
+		return
+	case []any:
+		y, ok := b.([]any)
+		if !ok {
+			break
+		}
+		if len(x) != len(y) {
+			if path != "" {
+				path += "."
+			}
+			*out = append(*out, path+"len")
+			return
+		}
+		for i := range x {
+			diffJSON(x[i], y[i], fmt.Sprintf("%s[%d]", path, i), out)
+		}
+		return
+	}
+	if !reflect.DeepEqual(a, b) {
+		*out = append(*out, path)
+	}
+}
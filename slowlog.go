@@ -0,0 +1,19 @@
+package orm
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+var slowQueryThreshold int64
+
+// SetSlowQueryThreshold logs statements running at least d as warnings
+// with their duration. Zero disables the warning.
+func SetSlowQueryThreshold(d time.Duration) {
+	atomic.StoreInt64(&slowQueryThreshold, int64(d))
+}
+
+// SlowQueryThreshold returns the threshold set by SetSlowQueryThreshold.
+func SlowQueryThreshold() time.Duration {
+	return time.Duration(atomic.LoadInt64(&slowQueryThreshold))
+}
@@ -0,0 +1,83 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var nullTimeType = reflect.TypeOf(sql.NullTime{})
+
+// Unscoped includes soft deleted rows in Find, First, Count, Paginate, List
+// and builder queries, and makes Delete remove rows for good.
+func Unscoped() QueryOption {
+	return queryOptionFunc(func(o *queryOptions) {
+		o.unscoped = true
+	})
+}
+
+// ForceDelete removes the rows of T matching where even when T soft deletes.
+func ForceDelete[T any](ctx context.Context, db Executor, where string, args ...any) (int64, error) {
+	return Delete[T](ctx, db, where, append(args, Unscoped())...)
+}
+
+// softDeleteColumn returns the column marking soft deleted rows of m: a field
+// tagged orm:"softDelete", else a deleted_at column holding a time. Models
+// without one are deleted for good.
+func softDeleteColumn(m *model) string {
+	column := ""
+	for _, f := range m.Fields {
+		if hasOrmOption(f.Tag, "softDelete") {
+			return f.Column
+		}
+		t := f.Type
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if f.Column == "deleted_at" && (t == timeType || t == nullTimeType) {
+			column = f.Column
+		}
+	}
+	return column
+}
+
+// scopedWhere adds the soft delete condition of T to where unless the call
+// is unscoped.
+func scopedWhere[T any](h *handle, where string, opts *queryOptions) string {
+	return scopedWhereOf(h, reflect.TypeOf(new(T)).Elem(), where, opts)
+}
+
+func scopedWhereOf(h *handle, t reflect.Type, where string, opts *queryOptions) string {
+	if opts.unscoped {
+		return where
+	}
+	column := softDeleteColumn(modelOf(h, t))
+	if column == "" {
+		return where
+	}
+	if where == "" {
+		return column + " IS NULL"
+	}
+	return "(" + where + ") AND " + column + " IS NULL"
+}
+
+// softDelete returns the UPDATE marking the rows matching where as deleted
+// with its arguments, or "" when T is deleted for good. The mark is the time
+// of h's clock when one is set, else CURRENT_TIMESTAMP.
+func softDelete[T any](h *handle, table, where string, args []any, opts *queryOptions) (string, []any) {
+	if opts.unscoped || strings.HasPrefix(strings.ToUpper(strings.TrimSpace(where)), "DELETE") {
+		return "", args
+	}
+	column := softDeleteColumn(modelOf(h, reflect.TypeOf(new(T)).Elem()))
+	if column == "" {
+		return "", args
+	}
+	mark := "CURRENT_TIMESTAMP"
+	if c := h.clockOf(); c != nil {
+		args = append(args, c.Now())
+		mark = fmt.Sprintf("$%d", len(args))
+	}
+	return "UPDATE " + table + " SET " + column + " = " + mark + " WHERE " + scopedWhere[T](h, where, opts), args
+}
@@ -0,0 +1,134 @@
+package orm
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLexSQL(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []token
+	}{
+		{"id = $12", []token{{kind: tokWord, text: "id"}, {kind: tokPunct, text: "="}, {kind: tokParam, text: "$12"}}},
+		{"x::int", []token{{kind: tokWord, text: "x"}, {kind: tokPunct, text: ":"}, {kind: tokPunct, text: ":"}, {kind: tokWord, text: "int"}}},
+		{"'a''$1' E'b\\'$2'", []token{{kind: tokString, text: "'a''$1'"}, {kind: tokString, text: "E'b\\'$2'"}}},
+		{`"a""$1"`, []token{{kind: tokQuotedIdent, text: `"a""$1"`}}},
+		{"$$ $1 $$ $f$ :a $f$", []token{{kind: tokDollarString, text: "$$ $1 $$"}, {kind: tokDollarString, text: "$f$ :a $f$"}}},
+		{"-- $1\n/* :a */", []token{{kind: tokComment, text: "-- $1"}, {kind: tokComment, text: "/* :a */"}}},
+		{"1.5e3 .5", []token{{kind: tokNumber, text: "1.5e3"}, {kind: tokNumber, text: ".5"}}},
+	}
+	for _, tt := range tests {
+		var got []token
+		for _, tok := range lexSQL(tt.sql) {
+			if tok.kind != tokSpace {
+				tok.line = 0
+				got = append(got, tok)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lexSQL(%q) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestParseSqlIn(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		args     []any
+		wantSql  string
+		wantArgs []any
+	}{
+		{"no params", "id IN (1, 2)", nil, "id IN (1, 2)", nil},
+		{"slice", "id IN $1", []any{[]int{1, 2}}, "id IN ($1, $2)", []any{1, 2}},
+		{"parenthesised", "id IN ( $1 )", []any{[]int{1, 2}}, "id IN ($1, $2)", []any{1, 2}},
+		{"mixed positional", "a = $1 AND id IN ($2) AND b = $3", []any{"x", []int{4, 5}, "y"},
+			"a = $1 AND id IN ($3, $4) AND b = $2", []any{"x", "y", 4, 5}},
+		{"list first", "id IN $1 AND status = $2", []any{[]string{"a", "b"}, "on"},
+			"id IN ($2, $3) AND status = $1", []any{"on", "a", "b"}},
+		{"reused in lists", "id IN $1 OR parent_id IN $1", []any{[]int{1, 2}},
+			"id IN ($1, $2) OR parent_id IN ($1, $2)", []any{1, 2}},
+		{"reused outside a list", "id IN $1 AND tags = $1", []any{[]string{"a"}},
+			"id IN ($2) AND tags = $1", []any{[]string{"a"}, "a"}},
+		{"empty slice", "id NOT IN $1 AND a = $2", []any{[]int{}, 3},
+			"id NOT IN (SELECT NULL WHERE FALSE) AND a = $1", []any{3}},
+		{"cast", "created_at > $2::timestamptz AND id IN ($1)", []any{[]int{1, 2}, "today"},
+			"created_at > $1::timestamptz AND id IN ($2, $3)", []any{"today", 1, 2}},
+		{"string and comment", "note = 'IN $1' AND id IN $1 -- IN $1\n", []any{[]int{7}},
+			"note = 'IN $1' AND id IN ($1) -- IN $1\n", []any{7}},
+		{"dollar quote", "body = $b$ IN $1 $b$ AND id IN $1", []any{[]int{7, 8}},
+			"body = $b$ IN $1 $b$ AND id IN ($1, $2)", []any{7, 8}},
+		{"bytes", "data IN $1", []any{[]byte("ab")}, "data IN $1", []any{[]byte("ab")}},
+		{"out of range", "id IN $2", []any{[]int{1}}, "id IN $2", []any{[]int{1}}},
+	}
+	for _, tt := range tests {
+		gotSql, gotArgs := parseSqlIn(tt.sql, tt.args)
+		if gotSql != tt.wantSql || !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+			t.Errorf("%s: parseSqlIn(%q, %v) = %q, %v; want %q, %v", tt.name, tt.sql, tt.args, gotSql, gotArgs, tt.wantSql, tt.wantArgs)
+		}
+	}
+}
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		args     []any
+		wantSql  string
+		wantArgs []any
+	}{
+		{"reordered", "a = $2 AND b = $1", []any{"x", "y"}, "a = ? AND b = ?", []any{"y", "x"}},
+		{"reused", "a = $1 OR b = $1", []any{"x"}, "a = ? OR b = ?", []any{"x", "x"}},
+		{"cast", "a = $1::text", []any{"x"}, "a = ?::text", []any{"x"}},
+		{"string", "a = '$1' AND b = $1", []any{"x"}, "a = '$1' AND b = ?", []any{"x"}},
+		{"comment", "/* $2 */ a = $1 -- $2", []any{"x"}, "/* $2 */ a = ? -- $2", []any{"x"}},
+		{"dollar quote", "$f$ $1 $f$ = $1", []any{"x"}, "$f$ $1 $f$ = ?", []any{"x"}},
+		{"out of range", "a = $1 AND b = $3", []any{"x"}, "a = ? AND b = $3", []any{"x"}},
+	}
+	for _, tt := range tests {
+		gotSql, gotArgs := rebind(MySQL, tt.sql, tt.args)
+		if gotSql != tt.wantSql || !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+			t.Errorf("%s: rebind(%q, %v) = %q, %v; want %q, %v", tt.name, tt.sql, tt.args, gotSql, gotArgs, tt.wantSql, tt.wantArgs)
+		}
+	}
+	sqlStr := "a = $2 AND b = $1"
+	if got, args := rebind(Postgres, sqlStr, []any{1, 2}); got != sqlStr || !reflect.DeepEqual(args, []any{1, 2}) {
+		t.Errorf("rebind(Postgres, %q) = %q, %v; want it unchanged", sqlStr, got, args)
+	}
+}
+
+func TestBindNamed(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		args     []any
+		wantSql  string
+		wantArgs []any
+	}{
+		{"no named args", "a = :a", []any{1}, "a = :a", []any{1}},
+		{"named", "status = :status AND age >= :age", []any{Named("status", "on"), Named("age", 18)},
+			"status = $1 AND age >= $2", []any{"on", 18}},
+		{"mixed positional", "id = $1 AND name = :name", []any{5, Named("name", "x")},
+			"id = $1 AND name = $2", []any{5, "x"}},
+		{"reused", "a = :v OR b = :v", []any{Named("v", 1)}, "a = $1 OR b = $1", []any{1}},
+		{"cast", "day::date = :day::date", []any{Named("day", "2024-01-02"), Named("date", "x")},
+			"day::date = $1::date", []any{"2024-01-02"}},
+		{"string and comment", "note = ':name' AND name = :name -- :name", []any{Named("name", "x")},
+			"note = ':name' AND name = $1 -- :name", []any{"x"}},
+		{"dollar quote", "body = $$:name$$ AND name = :name", []any{Named("name", "x")},
+			"body = $$:name$$ AND name = $1", []any{"x"}},
+		{"map", "a = :a AND b = $1", []any{2, NamedFrom(map[string]any{"a": 1})},
+			"a = $2 AND b = $1", []any{2, 1}},
+	}
+	for _, tt := range tests {
+		gotSql, gotArgs, err := bindNamed(nil, tt.sql, tt.args)
+		if err != nil || gotSql != tt.wantSql || !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+			t.Errorf("%s: bindNamed(%q, %v) = %q, %v, %v; want %q, %v", tt.name, tt.sql, tt.args, gotSql, gotArgs, err, tt.wantSql, tt.wantArgs)
+		}
+	}
+	if _, _, err := bindNamed(nil, "a = :a AND b = :b", []any{Named("a", 1)}); !errors.Is(err, ErrNamedParam) {
+		t.Errorf("bindNamed with a missing name: got %v, want ErrNamedParam", err)
+	}
+}
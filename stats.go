@@ -0,0 +1,40 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// TableStat is a snapshot of pg_stat_user_tables for one table.
+type TableStat struct {
+	Schema          string     `json:"schema"`
+	Table           string     `json:"table"`
+	RowEstimate     int64      `json:"row_estimate"`
+	LiveTuples      int64      `json:"live_tuples"`
+	DeadTuples      int64      `json:"dead_tuples"`
+	TotalBytes      int64      `json:"total_bytes"`
+	IndexBytes      int64      `json:"index_bytes"`
+	LastVacuum      *time.Time `json:"last_vacuum"`
+	LastAutovacuum  *time.Time `json:"last_autovacuum"`
+	LastAnalyze     *time.Time `json:"last_analyze"`
+	LastAutoanalyze *time.Time `json:"last_autoanalyze"`
+}
+
+const tableStatsSql = `SELECT s.schemaname AS schema, s.relname AS "table",
+	GREATEST(c.reltuples, 0)::bigint AS row_estimate,
+	s.n_live_tup AS live_tuples, s.n_dead_tup AS dead_tuples,
+	pg_total_relation_size(c.oid) AS total_bytes, pg_indexes_size(c.oid) AS index_bytes,
+	s.last_vacuum, s.last_autovacuum, s.last_analyze, s.last_autoanalyze
+FROM pg_stat_user_tables s JOIN pg_class c ON c.oid = s.relid
+ORDER BY total_bytes DESC`
+
+// TableStats reports size, tuple and maintenance statistics for every user
+// table, largest first.
+func TableStats(ctx context.Context, db *sql.DB) ([]TableStat, error) {
+	stats, err := Query[[]TableStat](ctx, db, tableStatsSql)
+	if err != nil {
+		return nil, err
+	}
+	return *stats, nil
+}
@@ -0,0 +1,179 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultFetchSize is the batch size used by Cursor when none is given.
+const DefaultFetchSize = 1000
+
+// Iterator yields rows one at a time instead of materialising a slice.
+//
+//	it, err := orm.Stream[User](ctx, db, "SELECT * FROM users")
+//	if err != nil { ... }
+//	defer it.Close()
+//	for it.Next() {
+//		u := it.Value()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type Iterator[T any] struct {
+	h     *handle
+	rows  *sql.Rows
+	plan  *scanPlan
+	cur   T
+	err   error
+	done  bool
+	batch int
+	seen  int
+	// fetch loads the next batch in cursor mode; nil for plain streams.
+	fetch   func() (*sql.Rows, error)
+	release func(failed bool) error
+}
+
+// Stream runs the query and returns an iterator over its rows. The driver
+// reads rows off the connection as Next is called, so client memory stays
+// bounded; the server still materialises the result unless Cursor is used.
+func Stream[T any](ctx context.Context, db Executor, sqlStr string, args ...any) (*Iterator[T], error) {
+	db = route[T](db)
+	h := handleFor(db)
+	sqlStr, args, err := bindNamed(h, sqlStr, args)
+	if err != nil {
+		return nil, err
+	}
+	sqlStr, args = parseSqlIn(sqlStr, args)
+	defer outputTimed(sqlStr, args, time.Now())
+	query, queryArgs := rebind(h.sqlDialect(), sqlStr, args)
+	rows, err := db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, wrapQueryError(err, sqlStr, "")
+	}
+	return &Iterator[T]{h: h, rows: rows}, nil
+}
+
+var cursorSeq int64
+
+// Cursor streams the query through a server-side cursor (DECLARE / FETCH)
+// inside its own transaction (a savepoint within a caller's *sql.Tx),
+// fetching fetchSize rows per round trip. Memory stays bounded on both ends
+// even without LIMIT. Close ends the transaction and must always be called.
+func Cursor[T any](ctx context.Context, db Executor, fetchSize int, sqlStr string, args ...any) (*Iterator[T], error) {
+	db = route[T](db)
+	if fetchSize <= 0 {
+		fetchSize = DefaultFetchSize
+	}
+	h := handleFor(db)
+	sqlStr, args, err := bindNamed(h, sqlStr, args)
+	if err != nil {
+		return nil, err
+	}
+	sqlStr, args = parseSqlIn(sqlStr, args)
+	tx, err := beginTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("orm_cursor_%d", atomic.AddInt64(&cursorSeq, 1))
+	declare := fmt.Sprintf("DECLARE %s NO SCROLL CURSOR FOR %s", name, sqlStr)
+	outputSql(declare, args)
+	query, queryArgs := rebind(h.sqlDialect(), declare, args)
+	if _, err = tx.ExecContext(ctx, query, queryArgs...); err != nil {
+		tx.Rollback()
+		return nil, wrapQueryError(err, declare, "")
+	}
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM %s", fetchSize, name)
+	it := &Iterator[T]{
+		h:     h,
+		batch: fetchSize,
+		fetch: func() (*sql.Rows, error) {
+			return tx.QueryContext(ctx, fetch)
+		},
+		release: func(failed bool) error {
+			if failed {
+				return tx.Rollback()
+			}
+			return tx.Commit()
+		},
+	}
+	if it.rows, err = it.fetch(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return it, nil
+}
+
+// Next advances to the next row, returning false at the end or on error.
+func (it *Iterator[T]) Next() bool {
+	if it.done || it.err != nil {
+		return false
+	}
+	for {
+		if it.rows.Next() {
+			it.seen++
+			if it.err = it.scan(); it.err != nil {
+				return false
+			}
+			return true
+		}
+		if it.err = it.rows.Err(); it.err != nil {
+			return false
+		}
+		if it.err = it.rows.Close(); it.err != nil {
+			return false
+		}
+		// A short batch means the cursor is exhausted.
+		if it.fetch == nil || it.seen < it.batch {
+			it.done = true
+			return false
+		}
+		it.seen = 0
+		if it.rows, it.err = it.fetch(); it.err != nil {
+			return false
+		}
+	}
+}
+
+func (it *Iterator[T]) scan() error {
+	var zero T
+	it.cur = zero
+	v := reflect.ValueOf(&it.cur).Elem()
+	if v.Kind() != reflect.Struct {
+		return it.rows.Scan(&it.cur)
+	}
+	if it.plan == nil {
+		columns, err := it.rows.Columns()
+		if err != nil {
+			return err
+		}
+		it.plan = newScanPlan(modelOf(it.h, v.Type()), columns)
+	}
+	return it.plan.scan(it.rows, v)
+}
+
+// Value returns the current row.
+func (it *Iterator[T]) Value() T {
+	return it.cur
+}
+
+// Err returns the first error met while iterating.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// Close releases the rows and, for cursors, ends the transaction. It is safe
+// to call more than once.
+func (it *Iterator[T]) Close() error {
+	err := it.rows.Close()
+	if it.release != nil {
+		release := it.release
+		it.release = nil
+		if rerr := release(it.err != nil); err == nil {
+			err = rerr
+		}
+	}
+	it.done = true
+	return err
+}
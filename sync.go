@@ -0,0 +1,91 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var ErrNoPrimaryKey = errors.New("orm: model has no primary key")
+
+// SyncResult counts the rows written by Sync.
+type SyncResult struct {
+	Inserted int
+	Updated  int
+	Deleted  int
+}
+
+// Sync makes the rows of T matching scopeWhere look like desired, in one
+// transaction: rows are matched by primary key, missing ones inserted,
+// changed ones updated and rows in scope absent from desired deleted. An
+// empty scopeWhere covers the whole table. Desired rows whose first key
+// column is zero are always inserted.
+func Sync[T any](ctx context.Context, db Executor, desired []T, scopeWhere string, args ...any) (SyncResult, error) {
+	var res SyncResult
+	m := modelOf(handleFor(route[T](db)), reflect.TypeOf(new(T)).Elem())
+	pk := m.primaryKey()
+	if len(pk) == 0 {
+		return res, ErrNoPrimaryKey
+	}
+	pkValues := func(row T) []any {
+		values := make([]any, len(pk))
+		for i, f := range pk {
+			values[i] = reflect.ValueOf(row).FieldByIndex(f.Index).Interface()
+		}
+		return values
+	}
+	key := func(row T) string {
+		return fmt.Sprint(pkValues(row))
+	}
+	conds := make([]string, len(pk))
+	for i, f := range pk {
+		conds[i] = fmt.Sprintf("%s = $%d", f.Column, i+1)
+	}
+	pkWhere := strings.Join(conds, " AND ")
+	err := Transaction(ctx, db, func(tx Executor) error {
+		existing, err := Find[T](ctx, tx, scopeWhere, args...)
+		if err != nil {
+			return err
+		}
+		var fresh, keyed []T
+		for _, row := range desired {
+			if reflect.ValueOf(row).FieldByIndex(pk[0].Index).IsZero() {
+				fresh = append(fresh, row)
+			} else {
+				keyed = append(keyed, row)
+			}
+		}
+		diff := DiffRows(existing, keyed, key)
+		if rows := append(fresh, diff.Added...); len(rows) > 0 {
+			if _, err = Insert(ctx, tx, rows); err != nil {
+				return err
+			}
+			res.Inserted = len(rows)
+		}
+		if len(diff.Changed) > 0 {
+			rows := make([]T, len(diff.Changed))
+			for i, c := range diff.Changed {
+				rows[i] = c.New
+			}
+			n, err := Update(ctx, tx, rows, "")
+			if err != nil {
+				return err
+			}
+			res.Updated = int(n)
+		}
+		for _, row := range diff.Removed {
+			n, err := Delete[T](ctx, tx, pkWhere, pkValues(row)...)
+			if err != nil {
+				return err
+			}
+			res.Deleted += int(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return SyncResult{}, err
+	}
+	return res, nil
+}
@@ -0,0 +1,115 @@
+package orm
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var plainIdent = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// reservedWords are the Postgres keywords that cannot name a table unquoted.
+var reservedWords = make(map[string]bool)
+
+func init() {
+	for _, word := range strings.Fields(`all analyse analyze and any array as asc asymmetric both case
+		cast check collate column constraint create current_catalog current_date current_role
+		current_time current_timestamp current_user default deferrable desc distinct do else end
+		except false fetch for foreign from grant group having in initially intersect into lateral
+		leading limit localtime localtimestamp not null offset on only or order placing primary
+		references returning select session_user some symmetric table then to trailing true union
+		unique user using variadic when where window with`) {
+		reservedWords[word] = true
+	}
+}
+
+// QuoteTable quotes the parts of a table name, optionally schema qualified
+// ("audit.events"), that are not plain lower case identifiers, so mixed case
+// and reserved names work. Parts already in double quotes are kept. Names
+// are quoted for Postgres; statements run through a handle with another
+// dialect quote with that dialect instead.
+func QuoteTable(name string) string {
+	return quoteTable(Postgres, name)
+}
+
+// quoteTable is QuoteTable with the identifier quoting of d. Parts in double
+// quotes are requoted for dialects that quote otherwise.
+func quoteTable(d Dialect, name string) string {
+	parts := splitQualified(name)
+	for i, part := range parts {
+		if strings.HasPrefix(part, `"`) && strings.HasSuffix(part, `"`) && len(part) > 1 {
+			if d.Name() != "postgres" {
+				parts[i] = d.QuoteIdent(strings.ReplaceAll(part[1:len(part)-1], `""`, `"`))
+			}
+			continue
+		}
+		if !plainIdent.MatchString(part) || reservedWords[part] {
+			if d.Name() == "postgres" {
+				parts[i] = pq.QuoteIdentifier(part)
+			} else {
+				parts[i] = d.QuoteIdent(part)
+			}
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// SplitTable splits a table name into its schema, "" when unqualified, and
+// table, with any quoting removed.
+func SplitTable(name string) (schema, table string) {
+	parts := splitQualified(name)
+	for i, part := range parts {
+		if strings.HasPrefix(part, `"`) && strings.HasSuffix(part, `"`) && len(part) > 1 {
+			parts[i] = strings.ReplaceAll(part[1:len(part)-1], `""`, `"`)
+		}
+	}
+	if len(parts) == 1 {
+		return "", parts[0]
+	}
+	return strings.Join(parts[:len(parts)-1], "."), parts[len(parts)-1]
+}
+
+// splitQualified splits name on the dots outside double quotes.
+func splitQualified(name string) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '"':
+			quoted = !quoted
+		case '.':
+			if !quoted {
+				parts = append(parts, name[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, name[start:])
+}
+
+// taggedTable applies the table and schema tags of the blank field of t to
+// the name returned by TableName, "" when there is none:
+//
+//	_ struct{} `schema:"audit" table:"events"`
+func taggedTable(t reflect.Type, table string) string {
+	schema := ""
+	for i := 0; i < t.NumField(); i++ {
+		if sf := t.Field(i); sf.Name == "_" {
+			if name := sf.Tag.Get("table"); name != "" && table == "" {
+				table = name
+			}
+			if name := sf.Tag.Get("schema"); name != "" {
+				schema = name
+			}
+		}
+	}
+	if table == "" {
+		table = toSnake(t.Name())
+	}
+	if schema != "" && len(splitQualified(table)) == 1 {
+		table = schema + "." + table
+	}
+	return table
+}
@@ -0,0 +1,155 @@
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var ErrTemplate = errors.New("orm: template")
+
+var identPart = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// SQLTemplate is a reusable statement with {{name}} slots, made by Template.
+type SQLTemplate struct {
+	// text holds the SQL around the slots: text[i] precedes slots[i].
+	text  []string
+	slots []string
+	err   error
+}
+
+// Vars fills the slots of a template. Identifiers and trusted snippets are
+// given as a Fragment (Ident, TableOf, ColumnOf, Raw); every other value
+// becomes a bind parameter, so it never reaches the SQL text.
+type Vars map[string]any
+
+// Fragment is SQL text spliced into a template slot, with its own $1..$n
+// parameters.
+type Fragment struct {
+	sql  string
+	args []any
+	err  error
+}
+
+// Template parses text, which refers to its slots as {{name}}. Slots inside
+// string literals and comments are left alone. Parse errors are reported by
+// Render.
+//
+//	byStatus := orm.Template("SELECT * FROM {{table}} WHERE {{cond}} ORDER BY {{order}} LIMIT {{limit}}")
+//	sqlStr, args, err := byStatus.Render(orm.Vars{
+//		"table": orm.TableOf[User](db),
+//		"cond":  orm.Raw("status = $1", "active"),
+//		"order": orm.ColumnOf[User]("CreatedAt"),
+//		"limit": 20,
+//	})
+func Template(text string) *SQLTemplate {
+	t := &SQLTemplate{}
+	var b strings.Builder
+	tokens := lexSQL(text)
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.kind == tokParam {
+			t.err = fmt.Errorf("%w: line %d: use a slot instead of %s", ErrTemplate, tok.line, tok.text)
+		}
+		if tok.text != "{" || i+1 >= len(tokens) || tokens[i+1].text != "{" {
+			b.WriteString(tok.text)
+			continue
+		}
+		var name string
+		j := i + 2
+		for ; j < len(tokens) && tokens[j].text != "}"; j++ {
+			if tokens[j].kind != tokSpace {
+				name += tokens[j].text
+			}
+		}
+		if j+1 >= len(tokens) || tokens[j+1].text != "}" || !identPart.MatchString(name) {
+			t.err = fmt.Errorf("%w: line %d: malformed slot", ErrTemplate, tok.line)
+			b.WriteString(tok.text)
+			continue
+		}
+		t.text = append(t.text, b.String())
+		t.slots = append(t.slots, name)
+		b.Reset()
+		i = j + 1
+	}
+	t.text = append(t.text, b.String())
+	return t
+}
+
+// Slots returns the slot names of t in order of appearance.
+func (t *SQLTemplate) Slots() []string {
+	return append([]string(nil), t.slots...)
+}
+
+// Render fills the slots of t from vars and returns the statement with its
+// arguments, ready for Query or Exec. Every slot needs a value.
+func (t *SQLTemplate) Render(vars Vars) (string, []any, error) {
+	if t.err != nil {
+		return "", nil, t.err
+	}
+	var b strings.Builder
+	var args []any
+	for i, name := range t.slots {
+		b.WriteString(t.text[i])
+		v, ok := vars[name]
+		if !ok {
+			return "", nil, fmt.Errorf("%w: no value for {{%s}}", ErrTemplate, name)
+		}
+		switch v := v.(type) {
+		case Fragment:
+			if v.err != nil {
+				return "", nil, fmt.Errorf("%w: {{%s}}: %v", ErrTemplate, name, v.err)
+			}
+			b.WriteString(shiftParams(v.sql, len(args)))
+			args = append(args, v.args...)
+		default:
+			args = append(args, v)
+			fmt.Fprintf(&b, "$%d", len(args))
+		}
+	}
+	b.WriteString(t.text[len(t.slots)])
+	return b.String(), args, nil
+}
+
+// Ident returns identifiers, optionally schema qualified, quoted where needed
+// and joined by commas. Names that are not plain identifiers are rejected.
+func Ident(names ...string) Fragment {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		for _, part := range strings.Split(name, ".") {
+			if !identPart.MatchString(part) {
+				return Fragment{err: fmt.Errorf("invalid identifier %q", name)}
+			}
+		}
+		quoted[i] = QuoteTable(name)
+	}
+	return Fragment{sql: strings.Join(quoted, ", ")}
+}
+
+// TableOf returns the table of T, quoted for the dialect of db.
+func TableOf[T any](db Executor) Fragment {
+	return Fragment{sql: quoteTable(handleFor(db).sqlDialect(), rawTableName(new(T)))}
+}
+
+// ColumnOf returns the columns of T named by field or column names, with
+// the default column naming.
+func ColumnOf[T any](names ...string) Fragment {
+	m := modelOf(nil, reflect.TypeOf(new(T)).Elem())
+	columns := make([]string, len(names))
+	for i, name := range names {
+		f, ok := m.selectable(name)
+		if !ok {
+			return Fragment{err: fmt.Errorf("%s has no field %q", m.Type.Name(), name)}
+		}
+		columns[i] = f.Column
+	}
+	return Fragment{sql: strings.Join(columns, ", ")}
+}
+
+// Raw returns a trusted snippet such as a condition, numbering its
+// parameters from $1; they are renumbered where it lands.
+func Raw(sql string, args ...any) Fragment {
+	return Fragment{sql: sql, args: args}
+}
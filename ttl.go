@@ -0,0 +1,159 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrNoTTL = errors.New("ttl: model has no field tagged ttl")
+
+// ParseTTL parses the value of a ttl tag. On top of time.ParseDuration units
+// it accepts d (days) and w (weeks), e.g. "30d" or "1w12h".
+func ParseTTL(s string) (time.Duration, error) {
+	var total time.Duration
+	rest := s
+	for rest != "" {
+		i := strings.IndexAny(rest, "dw")
+		if i < 0 {
+			d, err := time.ParseDuration(rest)
+			if err != nil {
+				return 0, fmt.Errorf("ttl: %q: %v", s, err)
+			}
+			total += d
+			break
+		}
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil {
+			return 0, fmt.Errorf("ttl: %q: %v", s, err)
+		}
+		unit := 24 * time.Hour
+		if rest[i] == 'w' {
+			unit *= 7
+		}
+		total += time.Duration(n) * unit
+		rest = rest[i+1:]
+	}
+	if total <= 0 {
+		return 0, fmt.Errorf("ttl: %q must be positive", s)
+	}
+	return total, nil
+}
+
+type reapTarget struct {
+	table  string
+	column string
+	ttl    time.Duration
+}
+
+// Reaper deletes rows whose ttl-tagged timestamp column is older than the
+// tag's duration, in rate limited batches.
+//
+//	type Session struct {
+//		ID        int64
+//		CreatedAt time.Time `ttl:"30d"`
+//	}
+type Reaper struct {
+	DB *sql.DB
+	// BatchSize rows are deleted per statement (default 1000).
+	BatchSize int
+	// Pause is slept between batches to limit load (default 100ms).
+	Pause time.Duration
+	// Interval separates sweeps in Run (default 1m).
+	Interval time.Duration
+
+	mu      sync.Mutex
+	targets []reapTarget
+}
+
+// NewReaper returns a Reaper using db.
+func NewReaper(db *sql.DB) *Reaper {
+	return &Reaper{DB: db}
+}
+
+// RegisterTTL adds T to the reaper. T must have a ttl-tagged field.
+func RegisterTTL[T any](r *Reaper) error {
+	for _, f := range modelOf(lookupHandle(r.DB), reflect.TypeOf(new(T)).Elem()).Fields {
+		tag := f.Tag.Get("ttl")
+		if tag == "" {
+			continue
+		}
+		ttl, err := ParseTTL(tag)
+		if err != nil {
+			return err
+		}
+		r.mu.Lock()
+		r.targets = append(r.targets, reapTarget{table: quoteTable(lookupHandle(r.DB).sqlDialect(), rawTableName(new(T))), column: f.Column, ttl: ttl})
+		r.mu.Unlock()
+		return nil
+	}
+	return ErrNoTTL
+}
+
+// Sweep deletes every expired row once and returns the count per table.
+func (r *Reaper) Sweep(ctx context.Context) (map[string]int64, error) {
+	r.mu.Lock()
+	targets := append([]reapTarget(nil), r.targets...)
+	r.mu.Unlock()
+	batch := r.BatchSize
+	if batch <= 0 {
+		batch = 1000
+	}
+	pause := r.Pause
+	if pause <= 0 {
+		pause = 100 * time.Millisecond
+	}
+	deleted := make(map[string]int64)
+	for _, t := range targets {
+		sqlStr := fmt.Sprintf("DELETE FROM %s WHERE ctid IN (SELECT ctid FROM %s WHERE %s < $1 LIMIT %d)", t.table, t.table, t.column, batch)
+		cutoff := lookupHandle(r.DB).now().Add(-t.ttl)
+		for {
+			outputSql(sqlStr, []any{cutoff})
+			result, err := r.DB.ExecContext(ctx, sqlStr, cutoff)
+			if err != nil {
+				return deleted, err
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return deleted, err
+			}
+			deleted[t.table] += n
+			if n < int64(batch) {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return deleted, ctx.Err()
+			case <-time.After(pause):
+			}
+		}
+	}
+	return deleted, nil
+}
+
+// Run sweeps every Interval until ctx is done. Errors are logged.
+func (r *Reaper) Run(ctx context.Context) error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Sweep(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("[ORM ERROR]\t reaper: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
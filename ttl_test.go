@@ -0,0 +1,25 @@
+package orm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTTL(t *testing.T) {
+	valid := map[string]time.Duration{
+		"30d":    30 * 24 * time.Hour,
+		"1w12h":  7*24*time.Hour + 12*time.Hour,
+		"90m":    90 * time.Minute,
+		"1d-12h": 12 * time.Hour,
+	}
+	for s, want := range valid {
+		if got, err := ParseTTL(s); err != nil || got != want {
+			t.Errorf("ParseTTL(%q) = %v, %v; want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "0s", "0d", "-1h", "-2160h", "-3d", "1d-25h", "1x", "d"} {
+		if got, err := ParseTTL(s); err == nil {
+			t.Errorf("ParseTTL(%q) = %v, want an error", s, got)
+		}
+	}
+}
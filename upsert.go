@@ -0,0 +1,62 @@
+package orm
+
+import (
+	"context"
+	"reflect"
+)
+
+// UpsertOptions picks the conflict target and the columns Upsert overwrites.
+type UpsertOptions struct {
+	// Conflict lists the columns of the unique index to resolve conflicts on.
+	// Empty uses the fields tagged `upsert:"key"`. The primary key is never
+	// implied: new rows would carry a zero serial id rather than their
+	// default, so name it here only when rows hold their own keys.
+	Conflict []string
+	// Update lists the columns overwritten on conflict; empty means every
+	// written column except the conflict columns, the primary key and fields
+	// tagged `upsert:"-"`.
+	Update []string
+	// DoNothing skips conflicting rows instead of updating them.
+	DoNothing bool
+}
+
+// Upsert inserts rows into T's table with INSERT ... ON CONFLICT (...) DO
+// UPDATE SET (or DO NOTHING) and returns the number of rows written.
+//
+//	type User struct {
+//		Id    int64  `json:"id"`
+//		Email string `json:"email" upsert:"key"`
+//		Name  string `json:"name"`
+//	}
+//	n, err := orm.Upsert(ctx, db, users, orm.UpsertOptions{})
+func Upsert[T any](ctx context.Context, db Executor, rows []T, opts UpsertOptions, options ...QueryOption) (int64, error) {
+	conflict := opts.Conflict
+	if len(conflict) == 0 {
+		conflict = conflictColumns(modelOf(handleFor(db), reflect.TypeOf(new(T)).Elem()))
+	}
+	if len(conflict) == 0 {
+		return 0, ErrMergeKeys
+	}
+	merge := MergeOptions{On: conflict, Update: opts.Update, DoNothing: opts.DoNothing}
+	return mergeRows(ctx, db, rows, merge, options, func(plan *mergePlan, table string) (string, error) {
+		server, err := serverVersion(ctx, db)
+		if err != nil {
+			return "", err
+		}
+		if err = server.Require(FeatureOnConflict); err != nil {
+			return "", err
+		}
+		return plan.upsertSql(table), nil
+	})
+}
+
+// conflictColumns returns the columns tagged `upsert:"key"`.
+func conflictColumns(m *model) []string {
+	var keys []string
+	for _, f := range m.Fields {
+		if f.Tag.Get("upsert") == "key" {
+			keys = append(keys, f.Column)
+		}
+	}
+	return keys
+}
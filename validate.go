@@ -0,0 +1,95 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FieldErrors maps Go field names to messages. It implements error so it can
+// be returned directly and rendered in API validation responses.
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + ": " + e[k]
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// ValidateUnique checks that no other row of T's table has the same value as
+// row in each of columns, ignoring row itself when its primary key is set. It
+// returns FieldErrors naming the taken fields, or nil. This is a friendly
+// pre-check; the unique constraint remains the authority under concurrency.
+func ValidateUnique[T any](ctx context.Context, db Executor, row T, columns ...string) error {
+	db = route[T](db)
+	h := handleFor(db)
+	v := reflect.ValueOf(row)
+	m := modelOf(h, v.Type())
+	var args []any
+	var exclude string
+	pk := m.primaryKey()
+	for _, f := range pk {
+		if v.FieldByIndex(f.Index).IsZero() {
+			continue
+		}
+		keys := make([]string, len(pk))
+		for i, k := range pk {
+			args = append(args, v.FieldByIndex(k.Index).Interface())
+			keys[i] = fmt.Sprintf("%s = $%d", k.Column, len(args))
+		}
+		exclude = " AND NOT (" + strings.Join(keys, " AND ") + ")"
+		break
+	}
+	table := quoteTable(h.sqlDialect(), rawTableName(new(T)))
+	checks := make([]string, len(columns))
+	fields := make([]*field, len(columns))
+	for i, name := range columns {
+		f, ok := m.selectable(name)
+		if !ok {
+			return fmt.Errorf("%w: unknown field %q", ErrFilter, name)
+		}
+		arg, cast, _, err := bindValue(reflect.StructField{Tag: f.Tag}, v.FieldByIndex(f.Index))
+		if err != nil {
+			return err
+		}
+		args = append(args, arg)
+		fields[i] = f
+		checks[i] = fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s = $%d%s%s)", table, f.Column, len(args), cast, exclude)
+	}
+	if len(checks) == 0 {
+		return nil
+	}
+	sqlStr := "SELECT " + strings.Join(checks, ", ")
+	sqlStr, args = rebind(h.sqlDialect(), sqlStr, args)
+	taken := make([]bool, len(checks))
+	targets := make([]any, len(checks))
+	for i := range taken {
+		targets[i] = &taken[i]
+	}
+	start := time.Now()
+	err := db.QueryRowContext(ctx, sqlStr, args...).Scan(targets...)
+	outputTimed(sqlStr, args, start)
+	if err != nil {
+		return wrapQueryError(err, sqlStr, table)
+	}
+	errs := FieldErrors{}
+	for i, f := range fields {
+		if taken[i] {
+			errs[f.Name] = "already taken"
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
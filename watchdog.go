@@ -0,0 +1,96 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+var ErrWatchdogAppName = errors.New("watchdog: ApplicationName is required")
+
+// StuckSession is a backend found idle in a transaction, or in a transaction
+// for too long, by the Watchdog.
+type StuckSession struct {
+	Pid         int64     `json:"pid"`
+	State       string    `json:"state"`
+	XactStart   time.Time `json:"xact_start"`
+	StateChange time.Time `json:"state_change"`
+	Query       string    `json:"query"`
+}
+
+// Watchdog samples pg_stat_activity for sessions tagged with ApplicationName
+// (set application_name in the connection string) and logs, or terminates,
+// the ones stuck idle in transaction beyond IdleThreshold or holding a
+// transaction open longer than MaxTxDuration.
+type Watchdog struct {
+	DB              *sql.DB
+	ApplicationName string
+	IdleThreshold   time.Duration
+	MaxTxDuration   time.Duration
+	Interval        time.Duration
+	// Cancel terminates idle sessions and cancels running statements of
+	// overdue transactions. Without it sessions are only logged.
+	Cancel bool
+}
+
+const stuckSessionsSql = `SELECT pid, state, xact_start, state_change, query
+FROM pg_stat_activity
+WHERE application_name = $1 AND pid <> pg_backend_pid() AND xact_start IS NOT NULL
+	AND ((state IN ('idle in transaction', 'idle in transaction (aborted)') AND now() - state_change > make_interval(secs => $2::float8))
+	OR ($3::float8 > 0 AND now() - xact_start > make_interval(secs => $3::float8)))`
+
+// Check samples once and returns the offending sessions, acting on them when
+// Cancel is set.
+func (w *Watchdog) Check(ctx context.Context) ([]StuckSession, error) {
+	if w.ApplicationName == "" {
+		return nil, ErrWatchdogAppName
+	}
+	idle := w.IdleThreshold
+	if idle <= 0 {
+		idle = time.Minute
+	}
+	sessions, err := Query[[]StuckSession](ctx, w.DB, stuckSessionsSql, w.ApplicationName, idle.Seconds(), w.MaxTxDuration.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range *sessions {
+		log.Printf("[ORM WARN]\t stuck session pid=%d state=%q xact_start=%s query=%q\n", s.Pid, s.State, s.XactStart.Format(time.RFC3339), s.Query)
+		if !w.Cancel {
+			continue
+		}
+		fn := "pg_cancel_backend"
+		if s.State != "active" {
+			fn = "pg_terminate_backend"
+		}
+		if _, err = w.DB.ExecContext(ctx, "SELECT "+fn+"($1)", s.Pid); err != nil {
+			return *sessions, err
+		}
+	}
+	return *sessions, nil
+}
+
+// Run checks every Interval (default 30s) until ctx is done. Check errors are
+// logged and do not stop the loop.
+func (w *Watchdog) Run(ctx context.Context) error {
+	if w.ApplicationName == "" {
+		return ErrWatchdogAppName
+	}
+	interval := w.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.Check(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("[ORM ERROR]\t watchdog: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}